@@ -0,0 +1,105 @@
+package crawler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+)
+
+// Exchange is a raw HTTP request and response, as handed to an Archiver.
+// Each response of a redirect chain is an exchange of its own, with the request it answered.
+type Exchange struct {
+	Request   *http.Request
+	Response  *http.Response // Its body has been consumed; use Body
+	Body      []byte         // Response body bytes as received
+	Truncated bool           // Body is incomplete, because of MaxBodySize or the content policy
+	IP        string         // Remote IP address, if known
+	Date      time.Time      // When the request was sent
+	Duration  time.Duration  // Time until the response headers arrived
+	Outlinks  []Link         // Links found in the body
+}
+
+// Archiver records the raw HTTP exchanges of a crawl, e.g. into a WARC file.
+// Implementations must be safe for concurrent use.
+type Archiver interface {
+	Archive(ex *Exchange) error
+}
+
+// archiveCapture collects what an Archiver needs while a request is in flight. The remote address
+// and timings are those of the latest hop when the request is redirected.
+type archiveCapture struct {
+	body      bytes.Buffer
+	ip        string
+	start     time.Time // When the hop's request was started
+	firstByte time.Time // When the hop's response began to arrive
+}
+
+// archiveCaptureKey is the context key of a request's archive capture
+type archiveCaptureKey struct{}
+
+// traceRequest attaches an archive capture to a request, so the remote address and timings of
+// each hop are recorded and checkRedirect can archive the redirect responses
+func traceRequest(req *http.Request) (*http.Request, *archiveCapture) {
+	capture := &archiveCapture{}
+	trace := &httptrace.ClientTrace{
+		GetConn: func(string) {
+			capture.start = time.Now()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			if addr, ok := info.Conn.RemoteAddr().(*net.TCPAddr); ok {
+				capture.ip = addr.IP.String()
+			}
+		},
+		GotFirstResponseByte: func() {
+			capture.firstByte = time.Now()
+		},
+	}
+	ctx := context.WithValue(req.Context(), archiveCaptureKey{}, capture)
+	return req.WithContext(httptrace.WithClientTrace(ctx, trace)), capture
+}
+
+// exchange returns the exchange of the latest hop
+func (ac *archiveCapture) exchange(resp *http.Response) *Exchange {
+	ex := &Exchange{
+		Request:  resp.Request,
+		Response: resp,
+		Body:     ac.body.Bytes(),
+		IP:       ac.ip,
+		Date:     ac.start,
+	}
+	if ac.firstByte.After(ac.start) {
+		ex.Duration = ac.firstByte.Sub(ac.start)
+	}
+	return ex
+}
+
+// archiveRedirect archives a redirect response while its body can still be read; the client
+// discards it once checkRedirect returns
+func (c *Crawler) archiveRedirect(resp *http.Response) {
+	capture, ok := resp.Request.Context().Value(archiveCaptureKey{}).(*archiveCapture)
+	if !ok {
+		return
+	}
+
+	hop := &archiveCapture{ip: capture.ip, start: capture.start, firstByte: capture.firstByte}
+	body := &countingReader{r: resp.Body}
+	if c.config.MaxBodySize > 0 {
+		body.r = io.LimitReader(resp.Body, c.config.MaxBodySize)
+	}
+	_, err := hop.body.ReadFrom(body)
+
+	ex := hop.exchange(resp)
+	ex.Truncated = err != nil || c.bodyTruncated(resp.Body, body.n)
+	c.archive(ex)
+}
+
+// archive hands a finished exchange to Config.Archiver
+func (c *Crawler) archive(ex *Exchange) {
+	if err := c.config.Archiver.Archive(ex); err != nil && c.ctx.Err() != context.Canceled {
+		c.config.Logger.Printf("Error archiving %s: %v", ex.Request.URL, err)
+	}
+}
@@ -0,0 +1,123 @@
+package crawler
+
+import (
+	"io"
+	"sync"
+)
+
+// Reasons a crawl stopped
+const (
+	StopCompleted   = "completed"    // The queue ran empty
+	StopCancelled   = "cancelled"    // Stop was called
+	StopMaxPages    = "max_pages"    // Config.MaxPages was reached
+	StopMaxBytes    = "max_bytes"    // Config.MaxBytes was reached
+	StopMaxDuration = "max_duration" // Config.MaxDuration elapsed
+)
+
+// budget tracks how much of the crawl budgets has been used
+type budget struct {
+	mu        sync.Mutex
+	pages     int
+	bytes     int64
+	perHost   map[string]int
+	exhausted string // Stop reason once a global budget is used up
+}
+
+// exhaust ends the crawl for the given reason; the first reason wins
+func (c *Crawler) exhaust(reason string) {
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+	if c.budget.exhausted == "" {
+		c.budget.exhausted = reason
+		c.config.Logger.Printf("Crawl budget reached (%s), finishing in-flight requests", reason)
+	}
+}
+
+// budgetExhausted reports whether a global budget has been used up
+func (c *Crawler) budgetExhausted() bool {
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+	return c.budget.exhausted != ""
+}
+
+// reservePage counts a page against the page budgets before it is fetched.
+// It returns false if the page must not be fetched.
+func (c *Crawler) reservePage(host string) bool {
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+
+	if c.budget.exhausted != "" {
+		return false
+	}
+	if c.config.MaxPages > 0 && c.budget.pages >= c.config.MaxPages {
+		c.budget.exhausted = StopMaxPages
+		c.config.Logger.Printf("Crawl budget reached (%s), finishing in-flight requests", StopMaxPages)
+		return false
+	}
+	if c.config.MaxPagesPerHost > 0 && c.budget.perHost[host] >= c.config.MaxPagesPerHost {
+		return false
+	}
+
+	c.budget.pages++
+	c.budget.perHost[host]++
+	return true
+}
+
+// reserveJob counts a job's page against the budgets like reservePage and marks the outstanding
+// job as reserved in the same step, so a checkpoint counts the page exactly once.
+// It returns false if the page must not be fetched.
+func (c *Crawler) reserveJob(j *Job) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.reservePage(HostOf(j.URL)) {
+		return false
+	}
+	j.Reserved = true
+	if _, ok := c.outstanding[j.URL]; ok {
+		c.outstanding[j.URL] = *j
+	}
+	return true
+}
+
+// addBytes counts downloaded bytes against the byte budget
+func (c *Crawler) addBytes(n int64) {
+	c.budget.mu.Lock()
+	c.budget.bytes += n
+	over := c.config.MaxBytes > 0 && c.budget.bytes >= c.config.MaxBytes
+	c.budget.mu.Unlock()
+
+	if over {
+		c.exhaust(StopMaxBytes)
+	}
+}
+
+// StopReason returns why the crawl stopped, or ended up stopping so far
+func (c *Crawler) StopReason() string {
+	c.budget.mu.Lock()
+	defer c.budget.mu.Unlock()
+	switch {
+	case c.budget.exhausted != "":
+		return c.budget.exhausted
+	case c.ctx.Err() != nil:
+		return StopCancelled
+	default:
+		return StopCompleted
+	}
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	eof bool // Set once the underlying reader is exhausted
+}
+
+// Read implements io.Reader
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.r.Read(p)
+	cr.n += int64(n)
+	if err == io.EOF {
+		cr.eof = true
+	}
+	return n, err
+}
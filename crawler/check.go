@@ -0,0 +1,73 @@
+package crawler
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// checkURL verifies that a link works without crawling it. It sends a HEAD request and, as some
+// servers reject or mishandle HEAD, confirms any failure with a GET whose body is not read.
+func (c *Crawler) checkURL(url string, depth int) (Result, error) {
+	return c.headWithFallback(url, func(method string) (Result, error) {
+		return c.requestStatus(method, url, depth)
+	})
+}
+
+// headWithFallback makes a request with HEAD and, if it fails, repeats it with GET
+func (c *Crawler) headWithFallback(url string, request func(method string) (Result, error)) (Result, error) {
+	result, err := request(http.MethodHead)
+	if err == nil || c.ctx.Err() != nil {
+		return result, err
+	}
+	c.config.Logger.Printf("HEAD %s failed (%v), retrying with GET", url, err)
+	return request(http.MethodGet)
+}
+
+// requestStatus requests a URL and records its status, size and type
+func (c *Crawler) requestStatus(method, url string, depth int) (Result, error) {
+	result := Result{
+		URL:       url,
+		Depth:     depth,
+		Timestamp: time.Now(),
+		Links:     []string{},
+		CheckOnly: true,
+	}
+
+	// Skip invalid URLs
+	if !IsURLValid(url) {
+		return result, fmt.Errorf("invalid URL: %s", url)
+	}
+
+	req, err := http.NewRequestWithContext(c.ctx, method, url, nil)
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	req, chain := trackRedirects(req)
+
+	// Make the request and let the throttle see how the host responded
+	host := req.URL.Host
+	requestStart := time.Now()
+	resp, err := c.client.Do(req)
+	setRedirects(&result, chain)
+	if err != nil {
+		if c.ctx.Err() == nil {
+			c.hosts.observe(host, time.Since(requestStart), 0, 0, err)
+		}
+		return result, err
+	}
+	resp.Body.Close()
+	c.hosts.observe(host, time.Since(requestStart), resp.StatusCode, retryAfter(resp), nil)
+
+	result.StatusCode = resp.StatusCode
+	result.ContentLength = resp.ContentLength
+	result.ContentType = MediaType(resp.Header.Get("Content-Type"))
+	result.Asset = result.ContentType != "" && !IsHTMLType(result.ContentType)
+
+	// Redirects have been followed, so anything but success is a broken link
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return result, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	return result, nil
+}
@@ -0,0 +1,237 @@
+package crawler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// checkpointFile is the name of the checkpoint inside Config.StateDir
+const checkpointFile = "checkpoint.json"
+
+// checkpointVersion is bumped whenever the checkpoint format changes
+const checkpointVersion = 2
+
+// checkpoint is a snapshot of a crawl that can be resumed. The results themselves are in the
+// results log (see resultLog), of which the checkpoint covers the first ResultsSize bytes.
+// A seen set kept on disk is rolled back to SeenGeneration; other seen sets are rebuilt.
+type checkpoint struct {
+	Version        int            `json:"version"`
+	SavedAt        time.Time      `json:"saved_at"`
+	Queue          []Job          `json:"queue"`
+	Results        int            `json:"results"`
+	ResultsSize    int64          `json:"results_size"`
+	SeenGeneration uint64         `json:"seen_generation,omitempty"`
+	Pages          int            `json:"pages"`
+	Bytes          int64          `json:"bytes"`
+	PerHost        map[string]int `json:"per_host"`
+}
+
+// checkpointPath returns the location of the checkpoint file
+func (c *Crawler) checkpointPath() string {
+	return filepath.Join(c.config.StateDir, checkpointFile)
+}
+
+// saveCheckpoint writes the current crawl state to Config.StateDir.
+// The file is replaced atomically, so an interruption never leaves a partial checkpoint.
+func (c *Crawler) saveCheckpoint() error {
+	state := checkpoint{
+		Version: checkpointVersion,
+		SavedAt: time.Now(),
+	}
+
+	// Take the queue, the end of the results log, the seen set and the budgets together so they
+	// agree with each other. Jobs keep their reservation, as their pages are already counted in the budgets.
+	c.stateMu.Lock()
+	durable, isDurable := c.seen.(durableSeenSet)
+	if isDurable {
+		generation, err := durable.Mark()
+		if err != nil {
+			c.stateMu.Unlock()
+			return err
+		}
+		state.SeenGeneration = generation
+	}
+	c.mu.Lock()
+	for _, j := range c.outstanding {
+		state.Queue = append(state.Queue, j)
+	}
+	state.Results = c.resultCount
+	size, err := c.resultLog.flush()
+	state.ResultsSize = size
+
+	c.budget.mu.Lock()
+	state.Pages = c.budget.pages
+	state.Bytes = c.budget.bytes
+	state.PerHost = make(map[string]int, len(c.budget.perHost))
+	for host, pages := range c.budget.perHost {
+		state.PerHost[host] = pages
+	}
+	c.budget.mu.Unlock()
+	c.mu.Unlock()
+	c.stateMu.Unlock()
+
+	// The checkpoint must not refer to results that could still be lost
+	if err == nil {
+		err = c.resultLog.sync()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write results log: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(c.config.StateDir, checkpointFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(state); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.checkpointPath()); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+
+	// Earlier checkpoints, and the generations of the seen set they need, are gone
+	if isDurable {
+		if err := durable.Release(state.SeenGeneration); err != nil {
+			return err
+		}
+	}
+
+	c.config.Logger.Printf("Checkpoint saved: %d queued, %d seen, %d results", len(state.Queue), c.seen.Len(), state.Results)
+	return nil
+}
+
+// resetSeen empties a seen set kept on disk, which may still hold the URLs of an earlier crawl
+func (c *Crawler) resetSeen() error {
+	if durable, ok := c.seen.(durableSeenSet); ok {
+		return durable.Reset()
+	}
+	return nil
+}
+
+// startState opens the results log in Config.StateDir for a new crawl, discarding the state
+// of any earlier one
+func (c *Crawler) startState() error {
+	if err := os.Remove(c.checkpointPath()); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to remove old checkpoint: %w", err)
+	}
+	if err := c.resetSeen(); err != nil {
+		return err
+	}
+	results, err := openResultLog(c.config.StateDir, 0)
+	if err != nil {
+		return err
+	}
+	c.resultLog = results
+	return nil
+}
+
+// restoreCheckpoint loads the crawl state from Config.StateDir and returns the jobs to queue.
+// A missing checkpoint is not an error; the crawl simply starts from its seeds.
+func (c *Crawler) restoreCheckpoint() ([]Job, error) {
+	data, err := os.ReadFile(c.checkpointPath())
+	if errors.Is(err, fs.ErrNotExist) {
+		c.config.Logger.Printf("No checkpoint in %s, starting a new crawl", c.config.StateDir)
+		return nil, c.startState()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint: %w", err)
+	}
+
+	var state checkpoint
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("failed to decode checkpoint: %w", err)
+	}
+	if state.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d", state.Version)
+	}
+
+	// A seen set on disk only needs the URLs added after the checkpoint removed. Others, and one
+	// that does not match the checkpoint, are rebuilt from the results and the queue.
+	rebuildSeen := true
+	if durable, ok := c.seen.(durableSeenSet); ok {
+		if state.SeenGeneration > 0 {
+			if err := durable.Rollback(state.SeenGeneration); err != nil {
+				c.config.Logger.Printf("Rebuilding the seen set: %v", err)
+			} else {
+				rebuildSeen = false
+			}
+		}
+		if rebuildSeen {
+			if err := durable.Reset(); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// Drop the results logged after the checkpoint and count the ones before it
+	results, err := openResultLog(c.config.StateDir, state.ResultsSize)
+	if err != nil {
+		return nil, err
+	}
+	c.resultLog = results
+
+	c.mu.Lock()
+	err = results.replay(func(r Result) error {
+		c.recordResult(r)
+		if rebuildSeen {
+			c.seen.Add(r.URL)
+			if r.FinalURL != "" {
+				c.seen.Add(r.FinalURL)
+			}
+		}
+		if r.ContentHash != "" && r.DuplicateOf == "" {
+			c.contentHashes[r.ContentHash] = r.URL
+		}
+		return nil
+	})
+	if err != nil {
+		c.mu.Unlock()
+		return nil, err
+	}
+	for _, j := range state.Queue {
+		if rebuildSeen {
+			c.seen.Add(j.URL)
+		}
+		c.outstanding[j.URL] = j
+	}
+	c.mu.Unlock()
+
+	c.budget.mu.Lock()
+	c.budget.pages = state.Pages
+	c.budget.bytes = state.Bytes
+	for host, pages := range state.PerHost {
+		c.budget.perHost[host] = pages
+	}
+	c.budget.mu.Unlock()
+
+	c.config.Logger.Printf("Resuming from checkpoint of %s: %d queued, %d seen, %d results",
+		state.SavedAt.Format(time.RFC3339), len(state.Queue), c.seen.Len(), state.Results)
+	return state.Queue, nil
+}
+
+// checkpointLoop saves a checkpoint every Config.CheckpointInterval until done is closed
+func (c *Crawler) checkpointLoop(done <-chan struct{}) {
+	ticker := time.NewTicker(c.config.CheckpointInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := c.saveCheckpoint(); err != nil {
+				c.config.Logger.Printf("Error saving checkpoint: %v", err)
+			}
+		case <-done:
+			return
+		}
+	}
+}
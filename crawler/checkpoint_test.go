@@ -0,0 +1,209 @@
+package crawler
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// testSitePages is the number of pages served by newTestSite
+const testSitePages = 11
+
+// newTestSite serves a home page linking to ten pages, each of which links home and to the next page
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		if r.URL.Path == "/" {
+			fmt.Fprint(w, "<html><title>Home</title>")
+			for i := 0; i < testSitePages-1; i++ {
+				fmt.Fprintf(w, `<a href="/p%d">page %d</a>`, i, i)
+			}
+			return
+		}
+		var n int
+		if _, err := fmt.Sscanf(r.URL.Path, "/p%d", &n); err != nil || n >= testSitePages-1 {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `<html><title>Page %d</title><a href="/">home</a><a href="/p%d">next</a>`, n, (n+1)%(testSitePages-1))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckpointResume(t *testing.T) {
+	tests := []struct {
+		name     string
+		seenSet  func(t *testing.T, dir string) SeenSet
+		afterRun func(t *testing.T, dir string, seen SeenSet) // Simulates a crash after the checkpoint
+		rollback bool                                         // The seen set is rolled back rather than rebuilt
+	}{
+		{
+			name:    "rebuilt seen set",
+			seenSet: func(*testing.T, string) SeenSet { return NewShardedSeenSet() },
+		},
+		{
+			name:     "disk seen set",
+			seenSet:  openTestDiskSeenSet,
+			rollback: true,
+		},
+		{
+			name:    "results logged after the checkpoint",
+			seenSet: func(*testing.T, string) SeenSet { return NewShardedSeenSet() },
+			afterRun: func(t *testing.T, dir string, _ SeenSet) {
+				appendToResultLog(t, dir, `{"url":"http://late/","status_code":200}`+"\n"+`{"url":"http://cut`)
+			},
+		},
+		{
+			name:    "disk seen set with URLs claimed after the checkpoint",
+			seenSet: openTestDiskSeenSet,
+			afterRun: func(t *testing.T, dir string, seen SeenSet) {
+				seen.Add("http://late/")
+				appendToResultLog(t, dir, `{"url":"http://late/","status_code":200}`+"\n")
+			},
+			rollback: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			site := newTestSite(t)
+			dir := t.TempDir()
+			config := Config{
+				StartURL:   site.URL + "/",
+				MaxDepth:   3,
+				NumWorkers: 2,
+				RateLimit:  time.Millisecond,
+				StateDir:   filepath.Join(dir, "state"),
+				Logger:     log.New(io.Discard, "", 0),
+			}
+
+			// The first run stops at its page budget, leaving jobs in the checkpoint
+			first := config
+			first.MaxPages = 4
+			first.SeenSet = tt.seenSet(t, dir)
+			c := New(first)
+			results, err := c.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.StopReason() != StopMaxPages {
+				t.Fatalf("first run stopped with %s, want %s", c.StopReason(), StopMaxPages)
+			}
+			if len(results) == 0 || len(results) >= testSitePages {
+				t.Fatalf("first run crawled %d pages, want part of the site", len(results))
+			}
+			state := readTestCheckpoint(t, config.StateDir)
+			if len(state.Queue) == 0 || state.Results != len(results) || state.Pages > first.MaxPages {
+				t.Errorf("checkpoint has %d queued, %d results and %d pages after %d results",
+					len(state.Queue), state.Results, state.Pages, len(results))
+			}
+			if tt.afterRun != nil {
+				tt.afterRun(t, dir, first.SeenSet)
+			}
+			closeTestSeenSet(t, first.SeenSet)
+
+			// The resumed run restores the results and crawls the rest of the site once
+			second := config
+			second.Resume = true
+			second.SeenSet = tt.seenSet(t, dir)
+			var logged bytes.Buffer
+			second.Logger = log.New(&logged, "", 0)
+			defer closeTestSeenSet(t, second.SeenSet)
+			c = New(second)
+			results, err = c.Start()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.StopReason() != StopCompleted {
+				t.Errorf("resumed run stopped with %s, want %s", c.StopReason(), StopCompleted)
+			}
+			if rebuilt := bytes.Contains(logged.Bytes(), []byte("Rebuilding the seen set")); tt.rollback && rebuilt {
+				t.Error("the disk seen set was rebuilt instead of rolled back")
+			}
+
+			crawled := make(map[string]int)
+			var urls []string
+			for _, result := range results {
+				crawled[result.URL]++
+				urls = append(urls, result.URL)
+			}
+			sort.Strings(urls)
+			if len(results) != testSitePages || len(crawled) != testSitePages {
+				t.Errorf("resumed crawl has %d results for %d URLs, want %d: %v", len(results), len(crawled), testSitePages, urls)
+			}
+			if crawled["http://late/"] != 0 {
+				t.Error("a result logged after the checkpoint was restored")
+			}
+			if second.SeenSet.Contains("http://late/") {
+				t.Error("a URL claimed after the checkpoint is still seen")
+			}
+			stats := c.Stats()
+			if stats.Pages != testSitePages || stats.Results != testSitePages {
+				t.Errorf("stats count %d pages and %d results, want %d", stats.Pages, stats.Results, testSitePages)
+			}
+			if state := readTestCheckpoint(t, config.StateDir); len(state.Queue) != 0 || state.Results != testSitePages {
+				t.Errorf("final checkpoint has %d queued and %d results", len(state.Queue), state.Results)
+			}
+		})
+	}
+}
+
+// openTestDiskSeenSet opens a DiskSeenSet kept in dir across runs
+func openTestDiskSeenSet(t *testing.T, dir string) SeenSet {
+	t.Helper()
+	s, err := NewDiskSeenSet(filepath.Join(dir, "seen.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+// closeTestSeenSet closes a seen set that keeps a file open
+func closeTestSeenSet(t *testing.T, seen SeenSet) {
+	t.Helper()
+	if s, ok := seen.(*DiskSeenSet); ok {
+		if err := s.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// appendToResultLog writes data to the end of the results log in a crawl's directory
+func appendToResultLog(t *testing.T, dir, data string) {
+	t.Helper()
+	file, err := os.OpenFile(filepath.Join(dir, "state", resultLogFile), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	if _, err := file.WriteString(data); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// readTestCheckpoint decodes the checkpoint in a state directory
+func readTestCheckpoint(t *testing.T, stateDir string) checkpoint {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(stateDir, checkpointFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state checkpoint
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatal(err)
+	}
+	if state.Version != checkpointVersion {
+		t.Errorf("checkpoint version = %d, want %d", state.Version, checkpointVersion)
+	}
+	return state
+}
@@ -0,0 +1,139 @@
+package crawler
+
+import (
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Content policy actions
+const (
+	ContentFetch    = "fetch" // Download in full
+	ContentHeadOnly = "head"  // Send a HEAD request for status, size and type only
+	ContentSkip     = "skip"  // Do not request at all
+)
+
+// extensionTypes maps common file extensions to MIME types, so URLs can be classified
+// before they are requested. The host's MIME database is deliberately not consulted: it maps
+// extensions such as .php to non-HTML types, and differs from one machine to the next.
+var extensionTypes = map[string]string{
+	".html": "text/html",
+	".htm":  "text/html",
+	".css":  "text/css",
+	".js":   "text/javascript",
+	".json": "application/json",
+	".xml":  "application/xml",
+	".txt":  "text/plain",
+	".pdf":  "application/pdf",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".svg":  "image/svg+xml",
+	".webp": "image/webp",
+	".ico":  "image/x-icon",
+	".zip":  "application/zip",
+	".tar":  "application/x-tar",
+	".gz":   "application/gzip",
+	".rar":  "application/vnd.rar",
+	".exe":  "application/octet-stream",
+	".mp3":  "audio/mpeg",
+	".mp4":  "video/mp4",
+	".avi":  "video/x-msvideo",
+	".mov":  "video/quicktime",
+	".mkv":  "video/x-matroska",
+	".doc":  "application/msword",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".xls":  "application/vnd.ms-excel",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".ppt":  "application/vnd.ms-powerpoint",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+}
+
+// ContentPolicy decides how resources are requested based on their MIME type.
+// Patterns are MIME types such as "application/pdf", wildcards such as "image/*", or "*".
+// The most specific matching pattern wins; HTML pages are always fetched and parsed.
+type ContentPolicy struct {
+	Fetch    []string // Downloaded in full and recorded as assets
+	HeadOnly []string // Requested with HEAD and recorded as assets
+	Skip     []string // Never requested
+}
+
+// DefaultContentPolicy crawls HTML pages only, like earlier versions of the crawler
+var DefaultContentPolicy = ContentPolicy{Skip: []string{"*"}}
+
+// Action returns what to do with a resource of the given MIME type.
+// Types that match no pattern are fetched.
+func (p ContentPolicy) Action(mediaType string) string {
+	mediaType = strings.ToLower(mediaType)
+	if IsHTMLType(mediaType) {
+		return ContentFetch
+	}
+
+	action, best := ContentFetch, -1
+	for _, rule := range []struct {
+		action   string
+		patterns []string
+	}{
+		{ContentFetch, p.Fetch},
+		{ContentHeadOnly, p.HeadOnly},
+		{ContentSkip, p.Skip},
+	} {
+		for _, pattern := range rule.patterns {
+			if score := matchMediaType(strings.ToLower(strings.TrimSpace(pattern)), mediaType); score > best {
+				action, best = rule.action, score
+			}
+		}
+	}
+	return action
+}
+
+// matchMediaType scores how specifically a pattern matches a MIME type:
+// 2 for an exact match, 1 for "type/*", 0 for "*" and -1 for no match
+func matchMediaType(pattern, mediaType string) int {
+	switch {
+	case pattern == mediaType:
+		return 2
+	case pattern == "*" || pattern == "*/*":
+		return 0
+	case strings.HasSuffix(pattern, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(pattern, "*")):
+		return 1
+	}
+	return -1
+}
+
+// IsHTMLType reports whether a MIME type is an HTML document
+func IsHTMLType(mediaType string) bool {
+	return mediaType == "text/html" || mediaType == "application/xhtml+xml"
+}
+
+// MediaType returns the MIME type of a Content-Type header without parameters
+func MediaType(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, _, _ = strings.Cut(contentType, ";")
+	}
+	return strings.ToLower(strings.TrimSpace(mediaType))
+}
+
+// GuessMediaType guesses the MIME type of a URL from its file extension.
+// It returns an empty string when the extension is not in extensionTypes, e.g. for dynamic
+// pages such as .php, whose type is only known from the response.
+func GuessMediaType(rawURL string) string {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return extensionTypes[strings.ToLower(path.Ext(parsedURL.Path))]
+}
+
+// contentAction returns the policy action for a URL based on its extension
+func (c *Crawler) contentAction(rawURL string) string {
+	mediaType := GuessMediaType(rawURL)
+	if mediaType == "" {
+		// Unknown until the server tells us, so request it
+		return ContentFetch
+	}
+	return c.config.ContentPolicy.Action(mediaType)
+}
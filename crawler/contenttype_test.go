@@ -0,0 +1,39 @@
+package crawler
+
+import "testing"
+
+func TestContentAction(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy ContentPolicy
+		url    string
+		want   string
+	}{
+		{"page without extension", DefaultContentPolicy, "https://example.com/about", ContentFetch},
+		{"html page", DefaultContentPolicy, "https://example.com/index.html", ContentFetch},
+		{"php page", DefaultContentPolicy, "https://example.com/index.php?id=1", ContentFetch},
+		{"asp page", DefaultContentPolicy, "https://example.com/default.asp", ContentFetch},
+		{"jsp page", DefaultContentPolicy, "https://example.com/app/view.jsp", ContentFetch},
+		{"unknown extension", DefaultContentPolicy, "https://example.com/file.xyz", ContentFetch},
+		{"known asset", DefaultContentPolicy, "https://example.com/report.PDF", ContentSkip},
+		{"known image", DefaultContentPolicy, "https://example.com/logo.png", ContentSkip},
+		{"head only", ContentPolicy{HeadOnly: []string{"image/*"}, Skip: []string{"*"}}, "https://example.com/logo.png", ContentHeadOnly},
+		{"fetched asset", ContentPolicy{Fetch: []string{"application/pdf"}, Skip: []string{"*"}}, "https://example.com/report.pdf", ContentFetch},
+		{"empty skip list", ContentPolicy{Skip: []string{}}, "https://example.com/report.pdf", ContentFetch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &Crawler{config: Config{ContentPolicy: tt.policy}}
+			if got := c.contentAction(tt.url); got != tt.want {
+				t.Errorf("contentAction(%q) = %q, want %q", tt.url, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKeepsEmptySkipList(t *testing.T) {
+	c := New(Config{StartURLs: []string{"https://example.com/"}, ContentPolicy: ContentPolicy{Skip: []string{}}})
+	if got := c.contentAction("https://example.com/report.pdf"); got != ContentFetch {
+		t.Errorf("contentAction with an empty skip list = %q, want %q", got, ContentFetch)
+	}
+}
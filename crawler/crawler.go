@@ -2,10 +2,11 @@ package crawler
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
-	"net/url"
+	"strings"
 	"sync"
 	"time"
 )
@@ -19,42 +20,151 @@ type Result struct {
 	Links         []string  `json:"links"`
 	Depth         int       `json:"depth"`
 	Timestamp     time.Time `json:"timestamp"`
+	Attempts      int       `json:"attempts"`
+	ContentType   string    `json:"content_type"`
+	// Asset is set for non-HTML resources, which are recorded but not parsed
+	Asset bool `json:"asset,omitempty"`
+	// Truncated is set when the body exceeded Config.MaxBodySize
+	Truncated bool `json:"truncated,omitempty"`
+	// SitemapLastMod and SitemapPriority are copied from the sitemap that listed the URL
+	SitemapLastMod  string  `json:"sitemap_lastmod,omitempty"`
+	SitemapPriority float64 `json:"sitemap_priority,omitempty"`
+	// BlockedByRobots is set when robots.txt disallowed fetching the URL
+	BlockedByRobots bool `json:"blocked_by_robots,omitempty"`
+	// Outlinks lists every link on the page with its anchor text, in document order
+	Outlinks []Link `json:"outlinks,omitempty"`
+	// Redirects lists the hops the request was redirected through, and FinalURL is where it
+	// ended up, when that differs from URL
+	Redirects []Redirect `json:"redirects,omitempty"`
+	FinalURL  string     `json:"final_url,omitempty"`
+	// Metadata holds the meta tags, headings and related URLs of HTML pages
+	Metadata *Metadata `json:"metadata,omitempty"`
+	// ContentHash is the SHA-256 of the HTML as received, and SimHash a fingerprint of its visible
+	// text (see SimHash) in hexadecimal. DuplicateOf names the first page crawled with the same content.
+	ContentHash string `json:"content_hash,omitempty"`
+	SimHash     string `json:"simhash,omitempty"`
+	DuplicateOf string `json:"duplicate_of,omitempty"`
+	// CheckOnly is set for links whose status was checked without crawling them (see Config.CheckLinks)
+	CheckOnly bool `json:"check_only,omitempty"`
+	// Error describes why the fetch failed, and ErrorCategory classifies it (see ClassifyError)
+	Error         string `json:"error,omitempty"`
+	ErrorCategory string `json:"error_category,omitempty"`
+}
+
+// Link is a hyperlink found on a page
+type Link struct {
+	URL  string `json:"url"`
+	Text string `json:"text"`
 }
 
 // Config holds all configuration parameters for the crawler
 type Config struct {
 	StartURL   string
+	StartURLs  []string // Additional seed URLs, crawled alongside StartURL
 	MaxDepth   int
 	NumWorkers int
 	Timeout    time.Duration
-	RateLimit  time.Duration
+	RateLimit  time.Duration // Minimum delay between requests to the same host
+	UserAgent  string
+	// MaxRedirects is the longest redirect chain followed, defaulting to 10
+	MaxRedirects int
+	// MaxConnsPerHost caps the number of concurrent requests to a single host
+	MaxConnsPerHost int
+	// AdaptiveThrottle adjusts each host's delay to its latency and to 429/503 responses
+	AdaptiveThrottle bool
+	MaxHostDelay     time.Duration // Upper bound for adaptive delays and Retry-After pauses
+	Retry            RetryPolicy
+	// AllowedDomains extends the crawl scope beyond the seed hosts; "*.example.com" matches subdomains
+	AllowedDomains []string
+	// Crawl budgets; zero means unlimited. The crawl stops cleanly when a global budget is reached.
+	MaxPages        int           // Total pages fetched
+	MaxBytes        int64         // Total bytes downloaded
+	MaxPagesPerHost int           // Pages fetched from a single host
+	MaxBodySize     int64         // Bytes read from a single response
+	MaxDuration     time.Duration // Wall-clock time of the crawl
+	// StateDir enables checkpoints of the queue and budgets to this directory, next to a log of the results
+	StateDir           string
+	CheckpointInterval time.Duration // How often to checkpoint, defaults to one minute
+	Resume             bool          // Continue from the checkpoint in StateDir, if there is one
+	// Frontier queues the jobs waiting to be crawled, defaulting to an unbounded in-memory queue
+	Frontier Frontier
+	// Ordering decides which job the default frontier returns next
+	Ordering Ordering
+	// SeenSet records queued and rejected URLs, defaulting to an exact in-memory set
+	SeenSet SeenSet
+	// ContentPolicy decides which non-HTML resources are fetched, checked with HEAD or skipped
+	ContentPolicy ContentPolicy
+	// Filter limits which discovered URLs are enqueued; seed URLs are always crawled
+	Filter *URLFilter
+	// RespectRobots enables robots.txt checks and Crawl-delay handling
+	RespectRobots bool
+	// Sitemaps seeds the queue with URLs from robots.txt sitemaps and /sitemap.xml
+	Sitemaps bool
+	// CheckLinks also verifies the links that are not crawled: links outside the crawl scope, beyond
+	// MaxDepth or of types the content policy skips are requested with HEAD, or GET if that fails,
+	// and recorded with CheckOnly set. They are not parsed or archived.
+	CheckLinks bool
 	Logger     *log.Logger
-	Storage    interface {
+	// Sink receives each result as soon as it is crawled. Results are then not kept in memory.
+	Sink ResultSink
+	// Archiver records every HTTP exchange. Responses are then fetched without transparent
+	// decompression, so the archive holds the body as the server encoded it.
+	Archiver Archiver
+	// Storage saves all results at once after the crawl; Sink is preferred
+	Storage interface {
 		Save(results interface{}) error
 	}
 }
 
+// ResultSink streams results to their destination while the crawl runs
+type ResultSink interface {
+	Open() error
+	Write(result Result) error
+	Close() error
+}
+
+// DefaultUserAgent is sent with every request unless Config.UserAgent is set
+const DefaultUserAgent = "goCrawler/1.0 (+https://github.com/Taiizor/goCrawler)"
+
+// metadataStorage is implemented by storages that can record crawl metadata next to the results
+type metadataStorage interface {
+	SetMetadata(key string, value interface{})
+}
+
 // Crawler represents the web crawler
 type Crawler struct {
-	config           Config
-	client           *http.Client
-	wg               sync.WaitGroup
-	seen             map[string]bool
-	results          []Result
-	jobs             chan job
-	stopChan         chan struct{}
-	ctx              context.Context
-	cancel           context.CancelFunc
-	rateLimiter      <-chan time.Time
-	mu               sync.Mutex
+	config         Config
+	client         *http.Client
+	wg             sync.WaitGroup
+	seen           SeenSet
+	outstanding    map[string]Job // Jobs queued or in progress when checkpointing, guarded by mu
+	results        []Result       // Kept unless a Sink makes them unnecessary, guarded by mu
+	keepResults    bool
+	resultLog      *resultLog        // Log of the results in Config.StateDir, guarded by mu
+	resultCount    int               // Results recorded, guarded by mu
+	blockedCount   int               // Results blocked by robots.txt, guarded by mu
+	redirectCount  int               // Results reached through redirects, guarded by mu
+	duplicateCount int               // Results with the same content as an earlier one, guarded by mu
+	contentHashes  map[string]string // First URL crawled per content hash, guarded by mu
+	statusCounts   map[int]int       // Responses per status code, guarded by mu
+	errorCounts    map[string]int    // Failed fetches per error category, guarded by mu
+	startedAt      time.Time         // Guarded by mu
+	seeds          []string          // Guarded by mu
+	sinkMu         sync.Mutex
+	sinkOpen       bool
+	frontier       Frontier
+	stopChan       chan struct{}
+	ctx            context.Context
+	cancel         context.CancelFunc
+	hosts          *hostScheduler
+	mu             sync.Mutex
+	// stateMu is held for reading from claiming URLs in the seen set until the jobs they belong to
+	// are recorded, and for writing by checkpoints, so a checkpoint never sees one without the other
+	stateMu          sync.RWMutex
 	pendingJobs      int        // Job counter
 	pendingJobsMutex sync.Mutex // Mutex for job counter
-}
-
-// job represents a URL to be crawled
-type job struct {
-	url   string
-	depth int
+	robots           *robotsCache
+	budget           budget
 }
 
 // New creates a new configured crawler
@@ -72,25 +182,127 @@ func New(config Config) *Crawler {
 	if config.RateLimit <= 0 {
 		config.RateLimit = 100 * time.Millisecond
 	}
+	if config.MaxConnsPerHost <= 0 {
+		config.MaxConnsPerHost = 2
+	}
+	if config.MaxRedirects <= 0 {
+		config.MaxRedirects = 10
+	}
+	if config.MaxHostDelay <= 0 {
+		config.MaxHostDelay = 30 * time.Second
+	}
+	config.Retry = config.Retry.withDefaults()
+	if config.ContentPolicy.Fetch == nil && config.ContentPolicy.HeadOnly == nil && config.ContentPolicy.Skip == nil {
+		config.ContentPolicy = DefaultContentPolicy
+	}
+	if config.Frontier == nil {
+		config.Frontier = NewOrderedFrontier(config.Ordering)
+	}
+	if config.SeenSet == nil {
+		config.SeenSet = NewShardedSeenSet()
+	}
+	if config.CheckpointInterval <= 0 {
+		config.CheckpointInterval = time.Minute
+	}
+	if config.UserAgent == "" {
+		config.UserAgent = DefaultUserAgent
+	}
 	if config.Logger == nil {
 		config.Logger = log.New(log.Writer(), "[CRAWLER] ", log.LstdFlags)
 	}
 
 	ctx, cancel := context.WithCancel(context.Background())
+	client := &http.Client{Timeout: config.Timeout}
+	if config.Archiver != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DisableCompression = true
+		client.Transport = transport
+	}
 
-	return &Crawler{
+	c := &Crawler{
 		config:           config,
-		client:           &http.Client{Timeout: config.Timeout},
-		seen:             make(map[string]bool),
+		client:           client,
+		seen:             config.SeenSet,
+		outstanding:      make(map[string]Job),
 		results:          make([]Result, 0),
-		jobs:             make(chan job, 1000),
+		keepResults:      config.Sink == nil,
+		statusCounts:     make(map[int]int),
+		errorCounts:      make(map[string]int),
+		contentHashes:    make(map[string]string),
+		frontier:         config.Frontier,
 		stopChan:         make(chan struct{}),
 		ctx:              ctx,
 		cancel:           cancel,
-		rateLimiter:      time.NewTicker(config.RateLimit).C,
+		budget:           budget{perHost: make(map[string]int)},
+		hosts:            newHostScheduler(config.RateLimit, config.MaxHostDelay, config.MaxConnsPerHost, config.AdaptiveThrottle, config.Logger),
 		pendingJobs:      0, // Initially 0 jobs
 		pendingJobsMutex: sync.Mutex{},
 	}
+
+	// Record redirect chains and stop at loops
+	client.CheckRedirect = c.checkRedirect
+
+	// Jobs the frontier loses will never complete
+	if notifier, ok := config.Frontier.(dropNotifier); ok {
+		notifier.OnDrop(c.jobsDropped)
+	}
+
+	// robots.txt is also where sitemaps are announced
+	if config.RespectRobots || config.Sitemaps {
+		c.robots = newRobotsCache(client, config.UserAgent, config.Logger)
+	}
+
+	return c
+}
+
+// Stats holds runtime statistics of a crawl
+type Stats struct {
+	Hosts    map[string]HostStats `json:"hosts"`
+	Rejected map[string]int       `json:"rejected"` // URLs rejected per filter rule
+	Pages    int                  `json:"pages"`    // Pages fetched, counted against MaxPages
+	Bytes    int64                `json:"bytes"`    // Bytes downloaded
+	Seen     int                  `json:"seen"`     // URLs in the seen set
+	Results  int                  `json:"results"`  // Results recorded, including ones only streamed to the Sink
+	Blocked  int                  `json:"blocked"`  // Results for URLs disallowed by robots.txt
+	// Redirected counts results that were reached through at least one redirect
+	Redirected int `json:"redirected"`
+	// Duplicates counts results with the same content as an earlier one
+	Duplicates int `json:"duplicates"`
+	// StatusCodes counts responses per HTTP status code
+	StatusCodes map[int]int `json:"status_codes"`
+	// Errors counts failed fetches per error category (see ClassifyError)
+	Errors map[string]int `json:"errors"`
+}
+
+// Stats returns a snapshot of the crawler's runtime statistics
+func (c *Crawler) Stats() Stats {
+	c.budget.mu.Lock()
+	stats := Stats{
+		Hosts:    c.hosts.stats(),
+		Rejected: map[string]int{},
+		Pages:    c.budget.pages,
+		Bytes:    c.budget.bytes,
+	}
+	c.budget.mu.Unlock()
+	stats.Seen = c.seen.Len()
+	c.mu.Lock()
+	stats.Results = c.resultCount
+	stats.Blocked = c.blockedCount
+	stats.Redirected = c.redirectCount
+	stats.Duplicates = c.duplicateCount
+	stats.StatusCodes = make(map[int]int, len(c.statusCounts))
+	for code, count := range c.statusCounts {
+		stats.StatusCodes[code] = count
+	}
+	stats.Errors = make(map[string]int, len(c.errorCounts))
+	for category, count := range c.errorCounts {
+		stats.Errors[category] = count
+	}
+	c.mu.Unlock()
+	if c.config.Filter != nil {
+		stats.Rejected = c.config.Filter.Rejections()
+	}
+	return stats
 }
 
 // incrementPendingJobs safely increments the job counter
@@ -101,47 +313,100 @@ func (c *Crawler) incrementPendingJobs() {
 	c.config.Logger.Printf("DEBUG: pendingJobs incremented to %d", c.pendingJobs)
 }
 
-// decrementPendingJobs safely decrements the job counter and closes the jobs channel if all jobs are done
+// decrementPendingJobs safely decrements the job counter and closes the frontier if all jobs are done
 func (c *Crawler) decrementPendingJobs() {
 	c.pendingJobsMutex.Lock()
 	defer c.pendingJobsMutex.Unlock()
 	c.pendingJobs--
 	c.config.Logger.Printf("DEBUG: pendingJobs decremented to %d", c.pendingJobs)
 	if c.pendingJobs <= 0 {
-		c.config.Logger.Println("All jobs completed, closing job channel")
-		// We can only close the channel once, so adding a check
+		c.config.Logger.Println("All jobs completed, closing the frontier")
+		// We only close the frontier once, so adding a check
 		if c.pendingJobs == 0 {
-			close(c.jobs)
+			c.frontier.Close()
 		}
 	}
 }
 
 // Start begins the crawling process
 func (c *Crawler) Start() ([]Result, error) {
-	// Parse and normalize the starting URL
-	startURL, err := NormalizeURL(c.config.StartURL)
+	// Parse and normalize the seed URLs
+	seeds, err := c.seedURLs()
 	if err != nil {
 		return nil, err
 	}
+	c.mu.Lock()
+	c.startedAt = time.Now()
+	c.seeds = seeds
+	c.mu.Unlock()
 
-	// Extract the base domain for filtering
-	baseURL, err := url.Parse(startURL)
+	// Pick up where an interrupted crawl left off, or start logging results for the checkpoints
+	var resumed []Job
+	switch {
+	case c.config.StateDir != "" && c.config.Resume:
+		resumed, err = c.restoreCheckpoint()
+	case c.config.StateDir != "":
+		err = c.startState()
+	default:
+		err = c.resetSeen()
+	}
 	if err != nil {
 		return nil, err
 	}
-	baseDomain := baseURL.Host
+
+	// Open the sink and replay the results restored from the checkpoint into it
+	if c.config.Sink != nil {
+		if err := c.openSink(); err != nil {
+			return nil, err
+		}
+	}
 
 	// Start the worker pool
 	for i := 0; i < c.config.NumWorkers; i++ {
 		c.wg.Add(1)
-		go c.worker(i+1, baseDomain)
+		go c.worker(i + 1)
 	}
 
-	// Enqueue the starting URL and increment job counter
-	c.config.Logger.Println("Adding starting URL to jobs queue")
+	// Stop cleanly once the time budget has elapsed
+	if c.config.MaxDuration > 0 {
+		timer := time.AfterFunc(c.config.MaxDuration, func() { c.exhaust(StopMaxDuration) })
+		defer timer.Stop()
+	}
+
+	// Checkpoint periodically while crawling
+	checkpointDone := make(chan struct{})
+	checkpointStopped := make(chan struct{})
+	if c.config.StateDir != "" {
+		go func() {
+			c.checkpointLoop(checkpointDone)
+			close(checkpointStopped)
+		}()
+	}
+
+	// Hold an extra pending job while seeding so the queue cannot close early
 	c.incrementPendingJobs()
-	c.jobs <- job{url: startURL, depth: 0}
-	c.markURLSeen(startURL)
+
+	// Jobs restored from a checkpoint are already tracked and only need queueing
+	c.pushJobs(resumed)
+
+	// Enqueue the seed URLs; each seed's host defines the scope of its crawl
+	c.config.Logger.Printf("Adding %d seed URLs to jobs queue", len(seeds))
+	sitemapHosts := make(map[string]bool)
+	for _, seed := range seeds {
+		host := HostOf(seed)
+		if c.enqueue(Job{URL: seed, Depth: 0, Scope: host}) == 0 {
+			continue
+		}
+
+		// Seed the queue from sitemaps in the background, once per host
+		if c.config.Sitemaps && !sitemapHosts[host] {
+			sitemapHosts[host] = true
+			c.incrementPendingJobs()
+			go c.discoverSitemaps(seed)
+		}
+	}
+
+	c.decrementPendingJobs()
 
 	// Wait for completion or cancellation
 	go func() {
@@ -152,11 +417,34 @@ func (c *Crawler) Start() ([]Result, error) {
 
 	select {
 	case <-c.stopChan:
-		c.config.Logger.Println("Crawling completed successfully")
+		c.config.Logger.Printf("Crawling completed (%s)", c.StopReason())
 	case <-c.ctx.Done():
 		c.config.Logger.Println("Crawling was cancelled")
 	}
 
+	// Save the final state, so an interrupted crawl can be resumed
+	close(checkpointDone)
+	if c.config.StateDir != "" {
+		<-checkpointStopped
+		if err := c.saveCheckpoint(); err != nil {
+			c.config.Logger.Printf("Error saving checkpoint: %v", err)
+		}
+		// Workers still finishing after a cancellation are not logged anymore
+		c.mu.Lock()
+		if err := c.resultLog.close(); err != nil {
+			c.config.Logger.Printf("Error closing results log: %v", err)
+		}
+		c.resultLog = nil
+		c.mu.Unlock()
+	}
+
+	// Finish the streamed output
+	if c.config.Sink != nil {
+		if err := c.closeSink(); err != nil {
+			return c.results, err
+		}
+	}
+
 	// Save results
 	if c.config.Storage != nil {
 		c.mu.Lock()
@@ -164,6 +452,12 @@ func (c *Crawler) Start() ([]Result, error) {
 		copy(results, c.results)
 		c.mu.Unlock()
 
+		// Record why the crawl ended when the storage can carry metadata
+		if m, ok := c.config.Storage.(metadataStorage); ok {
+			m.SetMetadata("stop_reason", c.StopReason())
+			m.SetMetadata("summary", c.Summary())
+		}
+
 		if err := c.config.Storage.Save(results); err != nil {
 			return results, errors.New("failed to save results: " + err.Error())
 		}
@@ -172,6 +466,28 @@ func (c *Crawler) Start() ([]Result, error) {
 	return c.results, nil
 }
 
+// seedURLs returns the normalized, de-duplicated seed URLs
+func (c *Crawler) seedURLs() ([]string, error) {
+	rawSeeds := c.config.StartURLs
+	if c.config.StartURL != "" {
+		rawSeeds = append([]string{c.config.StartURL}, rawSeeds...)
+	}
+
+	seeds := make([]string, 0, len(rawSeeds))
+	for _, rawSeed := range rawSeeds {
+		seed, err := NormalizeURL(strings.TrimSpace(rawSeed))
+		if err != nil {
+			return nil, err
+		}
+		seeds = append(seeds, seed)
+	}
+
+	if len(seeds) == 0 {
+		return nil, errors.New("no start URL given")
+	}
+	return seeds, nil
+}
+
 // Stop gracefully shuts down the crawler
 func (c *Crawler) Stop() {
 	c.cancel()
@@ -179,84 +495,372 @@ func (c *Crawler) Stop() {
 }
 
 // worker processes jobs from the queue
-func (c *Crawler) worker(id int, baseDomain string) {
+func (c *Crawler) worker(id int) {
 	defer c.wg.Done()
 	c.config.Logger.Printf("Worker %d started", id)
 
 	for {
-		select {
-		case <-c.ctx.Done():
-			c.config.Logger.Printf("Worker %d shutting down due to cancellation", id)
-			return
-		case currentJob, ok := <-c.jobs:
-			if !ok {
-				// This happens when the jobs channel is closed
-				c.config.Logger.Printf("Worker %d exiting, job channel closed", id)
-				return
+		currentJob, ok := c.frontier.Pop(c.ctx)
+		if !ok {
+			if c.ctx.Err() != nil {
+				c.config.Logger.Printf("Worker %d shutting down due to cancellation", id)
+			} else {
+				// This happens when the frontier is closed
+				c.config.Logger.Printf("Worker %d exiting, frontier closed", id)
 			}
+			return
+		}
+
+		// Once a global budget is used up, drain the queue without fetching.
+		// Drained jobs stay outstanding, so a resumed crawl with a larger budget picks them up.
+		if c.budgetExhausted() && !currentJob.Reserved {
+			c.decrementPendingJobs()
+			continue
+		}
 
-			// Rate limiting
-			<-c.rateLimiter
+		host := HostOf(currentJob.URL)
 
-			// Process the URL
-			c.config.Logger.Printf("Worker %d crawling %s (depth: %d)", id, currentJob.url, currentJob.depth)
-			result, err := c.crawlURL(currentJob.url, currentJob.depth)
+		// Check robots.txt and honour the host's Crawl-delay
+		if c.config.RespectRobots {
+			allowed, delay, err := c.robots.allowed(c.ctx, currentJob.URL)
 			if err != nil {
-				c.config.Logger.Printf("Error crawling %s: %v", currentJob.url, err)
-				c.decrementPendingJobs() // Job is considered completed even if there's an error
+				// The page may not be fetched without robots.txt, which fails it rather than blocks it
+				c.config.Logger.Printf("Worker %d not fetching %s: %v", id, currentJob.URL, err)
+				var unavailable *robotsUnavailableError
+				if errors.As(err, &unavailable) && c.config.Retry.shouldRetry(unavailable.statusCode, err, currentJob.Attempt+1) {
+					c.scheduleRetry(currentJob)
+					continue
+				}
+				c.completeJob(currentJob, &Result{
+					URL:           currentJob.URL,
+					Depth:         currentJob.Depth,
+					Timestamp:     time.Now(),
+					Links:         []string{},
+					Attempts:      currentJob.Attempt + 1,
+					Error:         err.Error(),
+					ErrorCategory: ClassifyError(0, err),
+				}, nil)
+				continue
+			}
+			if !allowed {
+				c.config.Logger.Printf("Worker %d skipping %s, disallowed by robots.txt", id, currentJob.URL)
+				c.completeJob(currentJob, &Result{
+					URL:             currentJob.URL,
+					Depth:           currentJob.Depth,
+					Timestamp:       time.Now(),
+					Links:           []string{},
+					BlockedByRobots: true,
+				}, nil)
+				continue
+			}
+			c.hosts.setCrawlDelay(host, delay)
+		}
+
+		// Count the page against the crawl budgets once, however often it is requeued
+		if !currentJob.Reserved && !c.reserveJob(&currentJob) {
+			c.config.Logger.Printf("Worker %d skipping %s, crawl budget reached", id, currentJob.URL)
+			if c.budgetExhausted() {
+				// Left outstanding for a resumed crawl, like the drained jobs
+				c.decrementPendingJobs()
+			} else {
+				// The host's budget is used up for good
+				c.completeJob(currentJob, nil, nil)
+			}
+			continue
+		}
+
+		// Per-host politeness: if the host is busy, let another host's job go first
+		if !c.hosts.tryAcquire(host) {
+			if !currentJob.deferred && c.requeue(currentJob) {
+				continue
+			}
+			if !c.hosts.acquire(c.ctx, host) {
+				c.decrementPendingJobs()
+				continue
+			}
+		}
+
+		// Process the URL
+		c.config.Logger.Printf("Worker %d crawling %s (depth: %d)", id, currentJob.URL, currentJob.Depth)
+		var result Result
+		var err error
+		if currentJob.Check {
+			result, err = c.checkURL(currentJob.URL, currentJob.Depth)
+		} else {
+			result, err = c.crawlURL(currentJob.URL, currentJob.Depth)
+		}
+		c.hosts.release(host)
+		result.Attempts = currentJob.Attempt + 1
+		result.SitemapLastMod = currentJob.LastMod
+		result.SitemapPriority = currentJob.Priority
+		if err != nil {
+			c.config.Logger.Printf("Error crawling %s: %v", currentJob.URL, err)
+			if c.ctx.Err() != nil {
+				// Cancelled: keep the job outstanding so a resumed crawl fetches it again
+				c.decrementPendingJobs()
+				continue
+			}
+			if errors.Is(err, errBlockedByRobots) {
+				c.config.Logger.Printf("Worker %d skipping %s, redirected to a URL disallowed by robots.txt", id, currentJob.URL)
+				result.BlockedByRobots = true
+				c.completeJob(currentJob, &result, nil)
+				continue
+			}
+			// An unavailable robots.txt of a redirect target is retried by its status
+			retryStatus := result.StatusCode
+			var unavailable *robotsUnavailableError
+			if errors.As(err, &unavailable) {
+				retryStatus = unavailable.statusCode
+			}
+			if c.config.Retry.shouldRetry(retryStatus, err, result.Attempts) {
+				// The job stays pending until the retry runs
+				c.scheduleRetry(currentJob)
 				continue
 			}
+			// Keep the failed page, with whatever was learned about it, in the results
+			result.Error = err.Error()
+			result.ErrorCategory = ClassifyError(result.StatusCode, err)
+			c.completeJob(currentJob, &result, nil)
+			continue
+		}
+
+		// If we haven't reached max depth, collect the links worth following, and when checking
+		// links, the ones to check. Once a budget is reached they are still queued, so a resumed
+		// crawl finds them in the checkpoint.
+		// A page reached through a redirect is only followed if it is in scope and new to the crawl;
+		// claiming its final URL keeps it from being crawled again under that address
+		c.stateMu.RLock()
+		followLinks := true
+		if result.FinalURL != "" {
+			if !c.inScope(result.FinalURL, currentJob.Scope) {
+				c.config.Logger.Printf("Worker %d not following %s, redirected out of scope to %s", id, currentJob.URL, result.FinalURL)
+				followLinks = false
+			} else if !c.seen.Add(result.FinalURL) {
+				c.config.Logger.Printf("Worker %d not following %s, redirected to already seen %s", id, currentJob.URL, result.FinalURL)
+				followLinks = false
+			}
+		}
 
-			// Store the result
-			c.mu.Lock()
-			c.results = append(c.results, result)
-			c.mu.Unlock()
-
-			// If we haven't reached max depth, add all links to the queue
-			if currentJob.depth < c.config.MaxDepth {
-				newJobsAdded := 0
-				for _, link := range result.Links {
-					// Only process URLs we haven't seen yet
-					if !c.hasURLBeenSeen(link) {
-						// Only follow links on the same domain
-						linkURL, err := url.Parse(link)
-						if err == nil && linkURL.Host == baseDomain {
-							c.markURLSeen(link)
-							// Increment counter before adding new job
-							c.incrementPendingJobs()
-							newJobsAdded++
-							select {
-							case c.jobs <- job{url: link, depth: currentJob.depth + 1}:
-								// Job successfully added
-							case <-c.ctx.Done():
-								c.decrementPendingJobs() // Decrement counter if job is cancelled
-								c.config.Logger.Printf("Worker %d context cancelled while adding job", id)
-								return
-							}
-						}
+		// The same goes for a page whose canonical URL is another one that was already seen, so
+		// query and slash variants don't spawn more variants. A canonical URL that is new to the
+		// crawl is queued at the page's depth. Pages with identical content are followed once.
+		var next []Job
+		if followLinks && result.Metadata != nil {
+			if canonical := result.Metadata.Canonical; canonical != "" && canonical != result.URL &&
+				canonical != result.FinalURL && c.inScope(canonical, currentJob.Scope) {
+				if c.hasURLBeenSeen(canonical) {
+					c.config.Logger.Printf("Worker %d not following %s, canonical URL %s already seen", id, currentJob.URL, canonical)
+					followLinks = false
+				} else if c.passesFilter(canonical) {
+					next = append(next, Job{URL: canonical, Depth: currentJob.Depth, Scope: currentJob.Scope, Inlinks: 1})
+				}
+			}
+		}
+		if result.ContentHash != "" {
+			if first, duplicate := c.claimContent(result.ContentHash, result.URL); duplicate {
+				c.config.Logger.Printf("Worker %d not following %s, same content as %s", id, currentJob.URL, first)
+				result.DuplicateOf = first
+				followLinks = false
+			}
+		}
+
+		if followLinks && (currentJob.Depth < c.config.MaxDepth || c.config.CheckLinks) {
+			for _, link := range result.Links {
+				if c.hasURLBeenSeen(link) {
+					// A queued URL may move up the queue as more pages link to it
+					if tracker, ok := c.frontier.(inlinkTracker); ok {
+						tracker.AddInlink(link)
 					}
+					continue
+				}
+				// Only follow links within the seed's scope that pass the URL filter and content policy
+				crawl := currentJob.Depth < c.config.MaxDepth && c.inScope(link, currentJob.Scope) && c.contentAction(link) != ContentSkip
+				if (crawl || c.config.CheckLinks) && c.passesFilter(link) {
+					next = append(next, Job{URL: link, Depth: currentJob.Depth + 1, Scope: currentJob.Scope, Inlinks: 1, Check: !crawl})
 				}
-				c.config.Logger.Printf("Worker %d added %d new jobs from %s", id, newJobsAdded, currentJob.url)
-			} else {
-				c.config.Logger.Printf("Worker %d reached max depth (%d) for %s", id, c.config.MaxDepth, currentJob.url)
 			}
+		} else {
+			c.config.Logger.Printf("Worker %d reached max depth (%d) for %s", id, c.config.MaxDepth, currentJob.URL)
+		}
+
+		// Store the result and queue its links, which also completes the job
+		added := c.recordJob(currentJob, &result, next)
+		c.stateMu.RUnlock()
+		newJobsAdded := c.dispatchJobs(&result, added)
+		c.config.Logger.Printf("Worker %d added %d new jobs from %s", id, newJobsAdded, currentJob.URL)
+	}
+}
+
+// enqueue queues new jobs, skipping URLs that were already seen.
+// It returns the number of jobs queued.
+func (c *Crawler) enqueue(jobs ...Job) int {
+	c.stateMu.RLock()
+	added := c.claimJobs(jobs)
+	c.mu.Lock()
+	c.trackJobs(added)
+	c.mu.Unlock()
+	c.stateMu.RUnlock()
+
+	c.pushJobs(added)
+	return len(added)
+}
 
-			// Job completed, decrement counter
+// completeJob finishes a job: its result is stored, its new jobs are queued and it leaves the
+// outstanding set in a single step, so checkpoints always see a consistent crawl state.
+// It returns the number of new jobs queued.
+func (c *Crawler) completeJob(done Job, result *Result, next []Job) int {
+	c.stateMu.RLock()
+	added := c.recordJob(done, result, next)
+	c.stateMu.RUnlock()
+	return c.dispatchJobs(result, added)
+}
+
+// recordJob stores a finished job's result, claims its new jobs and removes it from the outstanding set.
+// It returns the new jobs. Callers must hold stateMu for reading.
+func (c *Crawler) recordJob(done Job, result *Result, next []Job) []Job {
+	// Encode the result for the results log and claim the new URLs before taking the lock
+	var line []byte
+	if result != nil && c.config.StateDir != "" {
+		var err error
+		if line, err = json.Marshal(*result); err != nil {
+			c.config.Logger.Printf("Error encoding result for %s: %v", result.URL, err)
+		}
+	}
+
+	added := c.claimJobs(next)
+
+	c.mu.Lock()
+	if result != nil {
+		c.recordResult(*result)
+	}
+	if line != nil && c.resultLog != nil {
+		if err := c.resultLog.append(line); err != nil {
+			c.config.Logger.Printf("Error logging result for %s: %v", result.URL, err)
+		}
+	}
+	c.trackJobs(added)
+	delete(c.outstanding, done.URL)
+	c.mu.Unlock()
+	return added
+}
+
+// dispatchJobs streams a recorded result to the sink and queues the new jobs, which completes the
+// job they came from. It returns the number of new jobs queued.
+func (c *Crawler) dispatchJobs(result *Result, added []Job) int {
+	if result != nil {
+		c.writeResult(*result)
+	}
+
+	// Queue the new jobs before the finished one stops counting as pending
+	c.pushJobs(added)
+	c.decrementPendingJobs()
+	return len(added)
+}
+
+// recordResult counts a result and keeps it if needed. Callers must hold mu.
+func (c *Crawler) recordResult(result Result) {
+	c.resultCount++
+	if result.StatusCode != 0 {
+		c.statusCounts[result.StatusCode]++
+	}
+	if result.ErrorCategory != "" {
+		c.errorCounts[result.ErrorCategory]++
+	}
+	if len(result.Redirects) > 0 {
+		c.redirectCount++
+	}
+	if result.DuplicateOf != "" {
+		c.duplicateCount++
+	}
+	if result.BlockedByRobots {
+		c.blockedCount++
+	}
+	if c.keepResults {
+		c.results = append(c.results, result)
+	}
+}
+
+// claimContent records the URL crawled with a content hash. If the content was seen before,
+// it returns the first URL it was seen at and true.
+func (c *Crawler) claimContent(hash, url string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if first, ok := c.contentHashes[hash]; ok && first != url {
+		return first, true
+	}
+	c.contentHashes[hash] = url
+	return "", false
+}
+
+// claimJobs marks unseen jobs as seen, returning those that are new
+func (c *Crawler) claimJobs(jobs []Job) []Job {
+	added := make([]Job, 0, len(jobs))
+	for _, j := range jobs {
+		if c.seen.Add(j.URL) {
+			added = append(added, j)
+		}
+	}
+	return added
+}
+
+// trackJobs marks claimed jobs as outstanding for checkpoints. Callers must hold mu.
+func (c *Crawler) trackJobs(jobs []Job) {
+	if c.config.StateDir == "" {
+		return
+	}
+	for _, j := range jobs {
+		c.outstanding[j.URL] = j
+	}
+}
+
+// updateJob records a change to an outstanding job, such as another attempt, so a resumed
+// crawl picks it up in the same state
+func (c *Crawler) updateJob(j Job) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.outstanding[j.URL]; ok {
+		c.outstanding[j.URL] = j
+	}
+}
+
+// pushJobs counts jobs as pending and hands them to the workers
+func (c *Crawler) pushJobs(jobs []Job) {
+	for _, j := range jobs {
+		c.incrementPendingJobs()
+		if err := c.frontier.Push(j); err != nil {
+			// The job stays outstanding for the next checkpoint
+			c.config.Logger.Printf("Error queueing %s: %v", j.URL, err)
 			c.decrementPendingJobs()
 		}
 	}
 }
 
+// jobsDropped stops waiting for jobs the frontier lost. They stay outstanding, so a resumed crawl
+// queues them again.
+func (c *Crawler) jobsDropped(dropped int, err error) {
+	c.config.Logger.Printf("Error reading the queue, %d queued jobs lost: %v", dropped, err)
+	for i := 0; i < dropped; i++ {
+		c.decrementPendingJobs()
+	}
+}
+
+// requeue puts a job back at the end of the queue.
+// It returns false if the job should be processed now instead.
+func (c *Crawler) requeue(j Job) bool {
+	// Only worth it when other jobs are waiting
+	if c.frontier.Len() == 0 {
+		return false
+	}
+	j.deferred = true
+	return c.frontier.Push(j) == nil
+}
+
 // hasURLBeenSeen checks if a URL has already been seen
 func (c *Crawler) hasURLBeenSeen(url string) bool {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	return c.seen[url]
+	return c.seen.Contains(url)
 }
 
 // markURLSeen marks a URL as seen
 func (c *Crawler) markURLSeen(url string) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.seen[url] = true
+	c.seen.Add(url)
 }
@@ -0,0 +1,93 @@
+package crawler
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// Categories of failed fetches
+const (
+	ErrorDNS         = "dns"          // The host name could not be resolved
+	ErrorConnect     = "connect"      // The connection was refused, reset or could not be made
+	ErrorTLS         = "tls"          // The TLS handshake or certificate check failed
+	ErrorTimeout     = "timeout"      // The request or connection timed out
+	ErrorHTTP4xx     = "http_4xx"     // The server answered with a client error status
+	ErrorHTTP5xx     = "http_5xx"     // The server answered with a server error status
+	ErrorContentType = "content_type" // The response is of a type the content policy skips
+	ErrorParse       = "parse"        // The response body could not be read or parsed
+	// Redirects that lead back to a URL of the same chain, or that exceed Config.MaxRedirects
+	ErrorRedirectLoop     = "redirect_loop"
+	ErrorTooManyRedirects = "too_many_redirects"
+	ErrorOther            = "other" // Anything else, such as an unexpected status code
+)
+
+// categorizedError is an error whose category is known where it occurs
+type categorizedError struct {
+	category string
+	err      error
+}
+
+// Error implements error
+func (e *categorizedError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *categorizedError) Unwrap() error {
+	return e.err
+}
+
+// ClassifyError returns the category of a failed fetch from its status code and error.
+// It returns an empty string when nothing failed.
+func ClassifyError(statusCode int, err error) string {
+	switch {
+	case statusCode >= 500:
+		return ErrorHTTP5xx
+	case statusCode >= 400:
+		return ErrorHTTP4xx
+	case err == nil:
+		return ""
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorDNS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorTimeout
+	}
+
+	// Errors categorized where they occurred, such as parse errors, unless they were caused by a timeout
+	var categorized *categorizedError
+	if errors.As(err, &categorized) {
+		return categorized.category
+	}
+
+	// Certificate problems and handshake failures
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalidCert x509.CertificateInvalidError
+	var hostnameErr x509.HostnameError
+	var recordHeader tls.RecordHeaderError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalidCert) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &recordHeader) ||
+		strings.Contains(err.Error(), "tls: ") {
+		return ErrorTLS
+	}
+
+	var opErr *net.OpError
+	if (errors.As(err, &opErr) && opErr.Op == "dial") ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) {
+		return ErrorConnect
+	}
+
+	return ErrorOther
+}
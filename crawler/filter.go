@@ -0,0 +1,174 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Filter rule kinds
+const (
+	FilterRegex  = "regex"
+	FilterGlob   = "glob"
+	FilterPrefix = "prefix"
+)
+
+// noIncludeMatch is the rejection key for URLs that match none of the include rules
+const noIncludeMatch = "include: no rule matched"
+
+// FilterRule is a single include or exclude pattern.
+// Regex and glob rules match the whole normalized URL, prefix rules match the URL path.
+type FilterRule struct {
+	Kind    string
+	Pattern string
+	re      *regexp.Regexp
+}
+
+// ParseFilterRule parses a rule written as "regex:<expr>", "glob:<pattern>" or "prefix:<path>".
+// A rule without a kind is treated as a glob.
+func ParseFilterRule(spec string) (FilterRule, error) {
+	rule := FilterRule{Kind: FilterGlob, Pattern: spec}
+	if kind, pattern, found := strings.Cut(spec, ":"); found {
+		switch kind {
+		case FilterRegex, FilterGlob, FilterPrefix:
+			rule.Kind = kind
+			rule.Pattern = pattern
+		}
+	}
+
+	if rule.Pattern == "" {
+		return rule, fmt.Errorf("empty filter pattern in %q", spec)
+	}
+
+	var err error
+	switch rule.Kind {
+	case FilterRegex:
+		rule.re, err = regexp.Compile(rule.Pattern)
+	case FilterGlob:
+		rule.re, err = regexp.Compile(globToRegex(rule.Pattern))
+	}
+	if err != nil {
+		return rule, fmt.Errorf("invalid filter %q: %w", spec, err)
+	}
+	return rule, nil
+}
+
+// String returns the rule in the form accepted by ParseFilterRule
+func (r FilterRule) String() string {
+	return r.Kind + ":" + r.Pattern
+}
+
+// Match reports whether the rule matches a URL
+func (r FilterRule) Match(rawURL string) bool {
+	if r.Kind == FilterPrefix {
+		parsedURL, err := url.Parse(rawURL)
+		if err != nil {
+			return false
+		}
+		return strings.HasPrefix(parsedURL.Path, r.Pattern)
+	}
+	return r.re.MatchString(rawURL)
+}
+
+// globToRegex converts a glob to an anchored regular expression.
+// "**" matches anything, "*" matches anything except "/", "?" matches one character except "/".
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch ch := glob[i]; ch {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				b.WriteString(".*")
+				i++
+			} else {
+				b.WriteString("[^/]*")
+			}
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(ch)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// URLFilter decides which discovered URLs are enqueued and counts rejections per rule.
+// A URL is accepted when it matches no exclude rule and, if include rules exist, at least one of them.
+type URLFilter struct {
+	include  []FilterRule
+	exclude  []FilterRule
+	mu       sync.Mutex
+	rejected map[string]int
+}
+
+// NewURLFilter creates a filter from include and exclude rule specs (see ParseFilterRule)
+func NewURLFilter(include, exclude []string) (*URLFilter, error) {
+	f := &URLFilter{rejected: make(map[string]int)}
+	for _, spec := range include {
+		rule, err := ParseFilterRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		f.include = append(f.include, rule)
+	}
+	for _, spec := range exclude {
+		rule, err := ParseFilterRule(spec)
+		if err != nil {
+			return nil, err
+		}
+		f.exclude = append(f.exclude, rule)
+	}
+	return f, nil
+}
+
+// Allow reports whether a URL passes the filter, recording the rule that rejected it
+func (f *URLFilter) Allow(rawURL string) bool {
+	for _, rule := range f.exclude {
+		if rule.Match(rawURL) {
+			f.reject("exclude " + rule.String())
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+	for _, rule := range f.include {
+		if rule.Match(rawURL) {
+			return true
+		}
+	}
+	f.reject(noIncludeMatch)
+	return false
+}
+
+// reject counts a rejection under the given key
+func (f *URLFilter) reject(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rejected[key]++
+}
+
+// Rejections returns how many URLs each rule rejected
+func (f *URLFilter) Rejections() map[string]int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rejections := make(map[string]int, len(f.rejected))
+	for key, count := range f.rejected {
+		rejections[key] = count
+	}
+	return rejections
+}
+
+// passesFilter applies Config.Filter to a URL. Rejected URLs are marked seen so each is counted once.
+func (c *Crawler) passesFilter(link string) bool {
+	if c.config.Filter == nil || c.config.Filter.Allow(link) {
+		return true
+	}
+	c.markURLSeen(link)
+	return false
+}
@@ -0,0 +1,66 @@
+package crawler
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGlobToRegex(t *testing.T) {
+	tests := []struct {
+		glob string
+		url  string
+		want bool
+	}{
+		{"https://example.com/*", "https://example.com/page", true},
+		{"https://example.com/*", "https://example.com/docs/page", false},
+		{"https://example.com/**", "https://example.com/docs/page", true},
+		{"**/docs/**", "https://example.com/docs/intro", true},
+		{"**/docs/**", "https://example.com/blog/intro", false},
+		{"**.pdf", "https://example.com/files/report.pdf", true},
+		{"**.pdf", "https://example.com/files/report.pdf?download=1", false},
+		{"**/page?", "https://example.com/page1", true},
+		{"**/page?", "https://example.com/page10", false},
+		{"**/page?", "https://example.com/page/", false},
+		{"https://example.com/a+b(1).html", "https://example.com/a+b(1).html", true},
+		{"https://example.com/a+b(1).html", "https://example.com/aab1.html", false},
+		{"**/*.html", "https://example.com/index.html", true},
+	}
+	for _, tt := range tests {
+		re := regexp.MustCompile(globToRegex(tt.glob))
+		if got := re.MatchString(tt.url); got != tt.want {
+			t.Errorf("glob %q on %q = %v, want %v (regex %s)", tt.glob, tt.url, got, tt.want, re)
+		}
+	}
+}
+
+func TestFilterRuleMatch(t *testing.T) {
+	tests := []struct {
+		spec string
+		url  string
+		want bool
+	}{
+		{"glob:**/docs/**", "https://example.com/docs/intro", true},
+		{"**/docs/**", "https://example.com/docs/intro", true},
+		{"regex:/docs/", "https://example.com/docs/intro", true},
+		{"regex:^https://example\\.com/$", "https://example.com/docs/", false},
+		{"prefix:/docs", "https://example.com/docs/intro", true},
+		{"prefix:/docs", "https://example.com/blog/docs", false},
+	}
+	for _, tt := range tests {
+		rule, err := ParseFilterRule(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseFilterRule(%q): %v", tt.spec, err)
+		}
+		if got := rule.Match(tt.url); got != tt.want {
+			t.Errorf("rule %q on %q = %v, want %v", tt.spec, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterRuleErrors(t *testing.T) {
+	for _, spec := range []string{"", "glob:", "regex:(", "prefix:"} {
+		if _, err := ParseFilterRule(spec); err == nil {
+			t.Errorf("ParseFilterRule(%q) succeeded, want an error", spec)
+		}
+	}
+}
@@ -0,0 +1,366 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Job represents a URL waiting to be crawled
+type Job struct {
+	URL      string  `json:"url"`
+	Depth    int     `json:"depth"`
+	Scope    string  `json:"scope"`              // Host of the seed this job was reached from
+	Attempt  int     `json:"attempt,omitempty"`  // Number of failed attempts so far
+	LastMod  string  `json:"lastmod,omitempty"`  // From the sitemap that listed the URL
+	Priority float64 `json:"priority,omitempty"` // From the sitemap that listed the URL
+	Inlinks  int     `json:"inlinks,omitempty"`  // Links to the URL found so far
+	Reserved bool    `json:"reserved,omitempty"` // Set once the job was counted against the crawl budgets
+	Check    bool    `json:"check,omitempty"`    // Only check the link's status, see Config.CheckLinks
+	deferred bool    // Set once the job was requeued because its host was busy
+}
+
+// Frontier is the queue of jobs waiting to be crawled.
+// Implementations must be safe for concurrent use.
+type Frontier interface {
+	// Push adds a job to the queue
+	Push(j Job) error
+	// Pop blocks until a job is available and returns it. It returns false once the
+	// frontier is closed and empty, or when the context is cancelled.
+	Pop(ctx context.Context) (Job, bool)
+	// Len returns the number of queued jobs
+	Len() int
+	// Close wakes up all blocked Pop calls once the queue is empty
+	Close()
+}
+
+// inlinkTracker is implemented by frontiers that can reprioritize queued jobs as more links to them are found
+type inlinkTracker interface {
+	AddInlink(url string)
+}
+
+// dropNotifier is implemented by frontiers that can lose queued jobs, such as DiskFrontier when a
+// segment cannot be read back
+type dropNotifier interface {
+	OnDrop(fn func(dropped int, err error))
+}
+
+// signal wakes up goroutines waiting in Pop
+type signal struct {
+	ch chan struct{}
+}
+
+// newSignal creates a signal that has not fired yet
+func newSignal() signal {
+	return signal{ch: make(chan struct{})}
+}
+
+// broadcast wakes up every waiter and re-arms the signal. Callers must hold the frontier's lock.
+func (s *signal) broadcast() {
+	close(s.ch)
+	s.ch = make(chan struct{})
+}
+
+// MemoryFrontier is an unbounded in-memory queue
+type MemoryFrontier struct {
+	mu     sync.Mutex
+	queue  jobQueue
+	closed bool
+	wake   signal
+}
+
+// NewMemoryFrontier creates an empty in-memory FIFO frontier
+func NewMemoryFrontier() *MemoryFrontier {
+	return NewOrderedFrontier(Ordering{})
+}
+
+// NewOrderedFrontier creates an empty in-memory frontier that returns jobs in the given order
+func NewOrderedFrontier(o Ordering) *MemoryFrontier {
+	return &MemoryFrontier{queue: newJobQueue(o), wake: newSignal()}
+}
+
+// Push adds a job to the queue
+func (f *MemoryFrontier) Push(j Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queue.push(j)
+	f.wake.broadcast()
+	return nil
+}
+
+// Pop removes the next job from the queue, waiting for one if necessary
+func (f *MemoryFrontier) Pop(ctx context.Context) (Job, bool) {
+	for {
+		f.mu.Lock()
+		if j, ok := f.queue.pop(); ok {
+			f.mu.Unlock()
+			return j, true
+		}
+		if f.closed {
+			f.mu.Unlock()
+			return Job{}, false
+		}
+		wake := f.wake.ch
+		f.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return Job{}, false
+		}
+	}
+}
+
+// Len returns the number of queued jobs
+func (f *MemoryFrontier) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.queue.len()
+}
+
+// AddInlink records another link to a queued URL, which may move it up the queue
+func (f *MemoryFrontier) AddInlink(url string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queue.addInlink(url)
+}
+
+// Close wakes up all blocked Pop calls once the queue is empty
+func (f *MemoryFrontier) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	f.wake.broadcast()
+}
+
+// DiskFrontier is a queue that keeps up to a fixed number of jobs in memory and
+// spills the rest to segment files on disk, which are read back in the order they were written.
+// Jobs are ordered among those in memory, so the ordering is only approximate once the queue spills.
+type DiskFrontier struct {
+	mu          sync.Mutex
+	dir         string
+	memoryLimit int
+	segmentSize int
+	memory      jobQueue // Front of the queue
+	segments    []string // Full segment files, oldest first
+	writer      *os.File // Segment currently being written, if any
+	buffered    *bufio.Writer
+	written     int // Jobs in the segment being written
+	spilled     int // Jobs on disk in total
+	nextSegment int
+	closed      bool
+	wake        signal
+	onDrop      func(dropped int, err error)
+}
+
+// NewDiskFrontier creates a frontier that spills to dir once memoryLimit jobs are queued
+func NewDiskFrontier(dir string, memoryLimit int, o Ordering) (*DiskFrontier, error) {
+	if memoryLimit <= 0 {
+		memoryLimit = 10000
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create frontier directory: %w", err)
+	}
+	return &DiskFrontier{
+		dir:         dir,
+		memoryLimit: memoryLimit,
+		segmentSize: memoryLimit,
+		memory:      newJobQueue(o),
+		wake:        newSignal(),
+	}, nil
+}
+
+// Push adds a job to the queue, spilling to disk when memory is full
+func (f *DiskFrontier) Push(j Job) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Stay in memory until it is full and nothing is waiting on disk, to keep FIFO order
+	if f.spilled == 0 && f.memory.len() < f.memoryLimit {
+		f.memory.push(j)
+		f.wake.broadcast()
+		return nil
+	}
+
+	if err := f.spill(j); err != nil {
+		return err
+	}
+	f.wake.broadcast()
+	return nil
+}
+
+// spill appends a job to the current segment file. Callers must hold mu.
+func (f *DiskFrontier) spill(j Job) error {
+	if f.writer == nil {
+		path := filepath.Join(f.dir, fmt.Sprintf("frontier-%06d.jsonl", f.nextSegment))
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create frontier segment: %w", err)
+		}
+		f.nextSegment++
+		f.writer = file
+		f.buffered = bufio.NewWriter(file)
+		f.written = 0
+	}
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to encode job: %w", err)
+	}
+	data = append(data, '\n')
+	if _, err := f.buffered.Write(data); err != nil {
+		return fmt.Errorf("failed to write frontier segment: %w", err)
+	}
+	f.written++
+	f.spilled++
+
+	// Seal the segment once it is full
+	if f.written >= f.segmentSize {
+		return f.sealSegment()
+	}
+	return nil
+}
+
+// sealSegment closes the segment being written and queues it for reading. Callers must hold mu.
+func (f *DiskFrontier) sealSegment() error {
+	if f.writer == nil {
+		return nil
+	}
+	if err := f.buffered.Flush(); err != nil {
+		return fmt.Errorf("failed to flush frontier segment: %w", err)
+	}
+	if err := f.writer.Close(); err != nil {
+		return fmt.Errorf("failed to close frontier segment: %w", err)
+	}
+	f.segments = append(f.segments, f.writer.Name())
+	f.writer = nil
+	f.buffered = nil
+	return nil
+}
+
+// refill loads the oldest segment into memory. Callers must hold mu.
+func (f *DiskFrontier) refill() error {
+	if len(f.segments) == 0 {
+		// Read the partially written segment as well
+		if err := f.sealSegment(); err != nil {
+			return err
+		}
+	}
+	if len(f.segments) == 0 {
+		return nil
+	}
+
+	path := f.segments[0]
+	f.segments = f.segments[1:]
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open frontier segment: %w", err)
+	}
+	defer os.Remove(path)
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var j Job
+		if err := json.Unmarshal(scanner.Bytes(), &j); err != nil {
+			return fmt.Errorf("failed to decode job: %w", err)
+		}
+		f.memory.push(j)
+		f.spilled--
+	}
+	return scanner.Err()
+}
+
+// discard removes every job spilled to disk and returns how many there were. Callers must hold mu.
+func (f *DiskFrontier) discard() int {
+	dropped := f.spilled
+	for _, path := range f.segments {
+		os.Remove(path)
+	}
+	f.segments = nil
+	if f.writer != nil {
+		f.writer.Close()
+		os.Remove(f.writer.Name())
+		f.writer = nil
+		f.buffered = nil
+	}
+	f.spilled = 0
+	return dropped
+}
+
+// OnDrop sets a function to call with the number of jobs lost when spilled jobs cannot be read back
+func (f *DiskFrontier) OnDrop(fn func(dropped int, err error)) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.onDrop = fn
+}
+
+// Pop removes the next job from the queue, waiting for one if necessary
+func (f *DiskFrontier) Pop(ctx context.Context) (Job, bool) {
+	for {
+		f.mu.Lock()
+		if f.memory.len() == 0 && f.spilled > 0 {
+			if err := f.refill(); err != nil {
+				// A broken segment cannot be recovered; drop what is left on disk and say so
+				// outside the lock, as the crawler may close the frontier in response
+				dropped := f.discard()
+				onDrop := f.onDrop
+				f.mu.Unlock()
+				if onDrop != nil {
+					onDrop(dropped, err)
+				}
+				continue
+			}
+		}
+		if j, ok := f.memory.pop(); ok {
+			f.mu.Unlock()
+			return j, true
+		}
+		if f.closed {
+			f.mu.Unlock()
+			return Job{}, false
+		}
+		wake := f.wake.ch
+		f.mu.Unlock()
+
+		select {
+		case <-wake:
+		case <-ctx.Done():
+			return Job{}, false
+		}
+	}
+}
+
+// Len returns the number of queued jobs, in memory and on disk
+func (f *DiskFrontier) Len() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.memory.len() + f.spilled
+}
+
+// AddInlink records another link to a queued URL; jobs spilled to disk are not updated
+func (f *DiskFrontier) AddInlink(url string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.memory.addInlink(url)
+}
+
+// Close wakes up all blocked Pop calls once the queue is empty and removes leftover segments
+func (f *DiskFrontier) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+	if f.spilled == 0 {
+		if f.writer != nil {
+			f.writer.Close()
+			os.Remove(f.writer.Name())
+			f.writer = nil
+		}
+	}
+	f.wake.broadcast()
+}
@@ -0,0 +1,193 @@
+package crawler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// pushTestJobs queues jobs for http://a/0 to http://a/<n-1>
+func pushTestJobs(t *testing.T, f Frontier, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		if err := f.Push(Job{URL: fmt.Sprintf("http://a/%d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// popTestJobs pops jobs until the frontier is closed and empty and returns their URLs
+func popTestJobs(f Frontier) []string {
+	var urls []string
+	for {
+		j, ok := f.Pop(context.Background())
+		if !ok {
+			return urls
+		}
+		urls = append(urls, j.URL)
+	}
+}
+
+func TestDiskFrontierOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		memoryLimit int
+		jobs        int
+	}{
+		{"memory only", 10, 5},
+		{"exactly full", 5, 5},
+		{"one partial segment", 5, 7},
+		{"segment boundary", 3, 9},
+		{"many segments", 3, 20},
+		{"one job per segment", 1, 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f, err := NewDiskFrontier(dir, tt.memoryLimit, Ordering{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			pushTestJobs(t, f, tt.jobs)
+			if f.Len() != tt.jobs {
+				t.Errorf("Len() = %d, want %d", f.Len(), tt.jobs)
+			}
+
+			// Popping a job that was kept in memory leaves the spilled jobs counted
+			first, ok := f.Pop(context.Background())
+			if !ok || first.URL != "http://a/0" {
+				t.Fatalf("first Pop() = %q, %v", first.URL, ok)
+			}
+			if f.Len() != tt.jobs-1 {
+				t.Errorf("Len() after one Pop = %d, want %d", f.Len(), tt.jobs-1)
+			}
+
+			f.Close()
+			urls := append([]string{first.URL}, popTestJobs(f)...)
+			if len(urls) != tt.jobs {
+				t.Fatalf("popped %d jobs, want %d", len(urls), tt.jobs)
+			}
+			for i, url := range urls {
+				if want := fmt.Sprintf("http://a/%d", i); url != want {
+					t.Errorf("job %d = %s, want %s", i, url, want)
+				}
+			}
+			if f.Len() != 0 {
+				t.Errorf("Len() when empty = %d", f.Len())
+			}
+
+			// Segments are removed once they are read back
+			if segments, _ := filepath.Glob(filepath.Join(dir, "frontier-*.jsonl")); len(segments) != 0 {
+				t.Errorf("segments left behind: %v", segments)
+			}
+		})
+	}
+}
+
+func TestDiskFrontierInterleaved(t *testing.T) {
+	f, err := NewDiskFrontier(t.TempDir(), 2, Ordering{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Jobs pushed while others are on disk queue behind them
+	next := 0
+	var urls []string
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 3; i++ {
+			if err := f.Push(Job{URL: fmt.Sprintf("http://a/%d", next)}); err != nil {
+				t.Fatal(err)
+			}
+			next++
+		}
+		j, _ := f.Pop(context.Background())
+		urls = append(urls, j.URL)
+		if want := next - len(urls); f.Len() != want {
+			t.Errorf("round %d: Len() = %d, want %d", round, f.Len(), want)
+		}
+	}
+	f.Close()
+	urls = append(urls, popTestJobs(f)...)
+	for i, url := range urls {
+		if want := fmt.Sprintf("http://a/%d", i); url != want {
+			t.Fatalf("job %d = %s, want %s", i, url, want)
+		}
+	}
+}
+
+func TestDiskFrontierDrop(t *testing.T) {
+	// Ten jobs with room for three in memory: 0-2 in memory, segments of 3-5 and 6-8, and 9 being written
+	tests := []struct {
+		name        string
+		damage      func(path string) error
+		wantDropped int
+		wantPopped  int
+	}{
+		{
+			name:        "corrupt first line",
+			damage:      func(path string) error { return os.WriteFile(path, []byte("not json\n"), 0644) },
+			wantDropped: 4,
+			wantPopped:  6,
+		},
+		{
+			name: "corrupt later line",
+			damage: func(path string) error {
+				return os.WriteFile(path, []byte(`{"url":"http://a/6"}`+"\n{broken\n"+`{"url":"http://a/8"}`+"\n"), 0644)
+			},
+			wantDropped: 3,
+			wantPopped:  7,
+		},
+		{
+			name:        "missing segment",
+			damage:      os.Remove,
+			wantDropped: 4,
+			wantPopped:  6,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			f, err := NewDiskFrontier(dir, 3, Ordering{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var dropped, calls int
+			f.OnDrop(func(n int, err error) {
+				calls++
+				dropped += n
+				if err == nil {
+					t.Error("drop reported without an error")
+				}
+				// The callback runs without the frontier's lock held
+				f.Len()
+			})
+
+			pushTestJobs(t, f, 10)
+			if err := tt.damage(filepath.Join(dir, "frontier-000001.jsonl")); err != nil {
+				t.Fatal(err)
+			}
+
+			f.Close()
+			urls := popTestJobs(f)
+			if calls != 1 || dropped != tt.wantDropped {
+				t.Errorf("OnDrop called %d times with %d jobs, want once with %d", calls, dropped, tt.wantDropped)
+			}
+			if len(urls) != tt.wantPopped {
+				t.Fatalf("popped %v, want %d jobs", urls, tt.wantPopped)
+			}
+			for i, url := range urls[:6] {
+				if want := fmt.Sprintf("http://a/%d", i); url != want {
+					t.Errorf("job %d = %s, want %s", i, url, want)
+				}
+			}
+			if f.Len() != 0 {
+				t.Errorf("Len() after the drop = %d, want 0", f.Len())
+			}
+			if segments, _ := filepath.Glob(filepath.Join(dir, "frontier-*.jsonl")); len(segments) != 0 {
+				t.Errorf("segments left behind: %v", segments)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package crawler
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,8 +12,19 @@ import (
 	"github.com/PuerkitoBio/goquery"
 )
 
-// crawlURL fetches and processes a single URL
+// crawlURL fetches and processes a single URL. Assets that only need a status check are
+// requested with HEAD, falling back to GET like checkURL.
 func (c *Crawler) crawlURL(url string, depth int) (Result, error) {
+	if c.contentAction(url) == ContentHeadOnly {
+		return c.headWithFallback(url, func(method string) (Result, error) {
+			return c.fetchURL(method, url, depth)
+		})
+	}
+	return c.fetchURL(http.MethodGet, url, depth)
+}
+
+// fetchURL requests a URL with the given method and processes the response
+func (c *Crawler) fetchURL(method, url string, depth int) (Result, error) {
 	result := Result{
 		URL:       url,
 		Depth:     depth,
@@ -25,45 +38,116 @@ func (c *Crawler) crawlURL(url string, depth int) (Result, error) {
 	}
 
 	// Make the HTTP request
-	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, url, nil)
+	req, err := http.NewRequestWithContext(c.ctx, method, url, nil)
 	if err != nil {
 		return result, err
 	}
 
 	// Set a user agent to avoid being blocked by some sites
-	req.Header.Set("User-Agent", "goCrawler/1.0 (+https://github.com/Taiizor/goCrawler)")
+	req.Header.Set("User-Agent", c.config.UserAgent)
+
+	// Record the redirects the client follows
+	req, chain := trackRedirects(req)
 
-	// Make the request
+	// Note where the request went when it is archived
+	var capture *archiveCapture
+	if c.config.Archiver != nil {
+		req, capture = traceRequest(req)
+	}
+
+	// Make the request and let the throttle see how the host responded
+	host := req.URL.Host
+	requestStart := time.Now()
 	resp, err := c.client.Do(req)
+	setRedirects(&result, chain)
 	if err != nil {
+		if c.ctx.Err() == nil {
+			c.hosts.observe(host, time.Since(requestStart), 0, 0, err)
+		}
 		return result, err
 	}
 	defer resp.Body.Close()
+	duration := time.Since(requestStart)
+	c.hosts.observe(host, duration, resp.StatusCode, retryAfter(resp), nil)
 
 	// Record status code and content length
 	result.StatusCode = resp.StatusCode
 	result.ContentLength = resp.ContentLength
 
+	// Apply the per-response size limit and count downloaded bytes against the budget
+	body := &countingReader{r: resp.Body}
+	if c.config.MaxBodySize > 0 {
+		body.r = io.LimitReader(resp.Body, c.config.MaxBodySize)
+	}
+	defer func() { c.addBytes(body.n) }()
+
+	// Keep a copy of the bytes read for the archive, which is written once the response is processed
+	if capture != nil {
+		body.r = io.TeeReader(body.r, &capture.body)
+		defer func() {
+			ex := capture.exchange(resp)
+			ex.Truncated = result.Truncated || (method == http.MethodGet && !body.eof)
+			ex.Outlinks = result.Outlinks
+			c.archive(ex)
+		}()
+	}
+
 	// Only process successful responses
 	if resp.StatusCode != http.StatusOK {
+		// Error pages are archived too
+		if capture != nil {
+			io.Copy(io.Discard, body)
+		}
 		return result, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	// Only process HTML content
-	contentType := resp.Header.Get("Content-Type")
-	if !strings.Contains(strings.ToLower(contentType), "text/html") {
-		return result, fmt.Errorf("non-HTML content type: %s", contentType)
+	// Fall back to the URL's extension when the server sends no Content-Type
+	result.ContentType = MediaType(resp.Header.Get("Content-Type"))
+	if result.ContentType == "" {
+		result.ContentType = GuessMediaType(url)
+	}
+	if result.ContentType == "" {
+		result.ContentType = "application/octet-stream"
+	}
+
+	// Anything that isn't an HTML page is recorded as an asset
+	if method == http.MethodHead || !IsHTMLType(result.ContentType) {
+		result.Asset = true
+		action := c.config.ContentPolicy.Action(result.ContentType)
+
+		// The URL didn't look like a skipped type, but the response is one
+		if method == http.MethodGet && action == ContentSkip {
+			return result, &categorizedError{ErrorContentType, fmt.Errorf("skipped content type: %s", result.ContentType)}
+		}
+		if method == http.MethodGet && action == ContentFetch {
+			size, err := io.Copy(io.Discard, body)
+			if err != nil {
+				return result, err
+			}
+			result.ContentLength = size
+			result.Truncated = c.bodyTruncated(resp.Body, size)
+		}
+		return result, nil
 	}
 
-	// Parse the HTML document
-	doc, err := goquery.NewDocumentFromReader(resp.Body)
+	// Parse the HTML document, hashing it on the way to detect exact duplicates
+	hash := sha256.New()
+	doc, err := goquery.NewDocumentFromReader(io.TeeReader(body, hash))
 	if err != nil {
-		return result, err
+		return result, &categorizedError{ErrorParse, err}
 	}
+	result.Truncated = c.bodyTruncated(resp.Body, body.n)
+	result.ContentHash = hex.EncodeToString(hash.Sum(nil))
 
 	// Extract the title
 	result.Title = strings.TrimSpace(doc.Find("title").Text())
 
+	// URLs are resolved against the URL the page was finally served from
+	base := resp.Request.URL.String()
+
+	// Extract the description, canonical URL, social tags, headings and alternates
+	result.Metadata = extractMetadata(doc, base)
+
 	// Extract all links
 	doc.Find("a[href]").Each(func(i int, s *goquery.Selection) {
 		// Get the href attribute
@@ -73,9 +157,9 @@ func (c *Crawler) crawlURL(url string, depth int) (Result, error) {
 		}
 
 		// Resolve relative URLs
-		absoluteURL, err := ResolveURL(url, href)
+		absoluteURL, err := ResolveURL(base, href)
 		if err != nil {
-			c.config.Logger.Printf("Error resolving URL %s against %s: %v", href, url, err)
+			c.config.Logger.Printf("Error resolving URL %s against %s: %v", href, base, err)
 			return
 		}
 
@@ -93,11 +177,36 @@ func (c *Crawler) crawlURL(url string, depth int) (Result, error) {
 
 		// Add the link to the results
 		result.Links = append(result.Links, normalizedURL)
+		result.Outlinks = append(result.Outlinks, Link{URL: normalizedURL, Text: strings.Join(strings.Fields(s.Text()), " ")})
 	})
 
+	// Fingerprint the visible text to detect near-duplicates
+	doc.Find("script, style, noscript, template").Remove()
+	if fingerprint := SimHash(doc.Find("body").Text()); fingerprint != 0 {
+		result.SimHash = fmt.Sprintf("%016x", fingerprint)
+	}
+
 	return result, nil
 }
 
+// bodyTruncated reports whether the response body was cut off at Config.MaxBodySize
+func (c *Crawler) bodyTruncated(body io.Reader, read int64) bool {
+	if c.config.MaxBodySize <= 0 || read < c.config.MaxBodySize {
+		return false
+	}
+	var probe [1]byte
+	n, _ := io.ReadFull(body, probe[:])
+	return n > 0
+}
+
+// retryAfter returns the Retry-After delay of an overload response
+func retryAfter(resp *http.Response) time.Duration {
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusServiceUnavailable {
+		return 0
+	}
+	return parseRetryAfter(resp.Header.Get("Retry-After"))
+}
+
 // FetchURLContent is a simplified HTTP client for fetching content directly
 func FetchURLContent(url string, timeout time.Duration) (string, error) {
 	client := &http.Client{
@@ -0,0 +1,113 @@
+package crawler
+
+import (
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Metadata holds the tags of an HTML page that describe it to search engines and social networks
+type Metadata struct {
+	Description string `json:"description,omitempty"` // <meta name="description">
+	Robots      string `json:"robots,omitempty"`      // <meta name="robots">
+	Lang        string `json:"lang,omitempty"`        // <html lang>
+	// Canonical is the page's <link rel="canonical"> URL
+	Canonical string `json:"canonical,omitempty"`
+	// OpenGraph and Twitter map og:* and twitter:* properties, such as og:title, to their
+	// content; when a property repeats, the first value is kept
+	OpenGraph map[string]string `json:"opengraph,omitempty"`
+	Twitter   map[string]string `json:"twitter,omitempty"`
+	// H1, H2 and H3 hold the text of the page's headings in document order
+	H1 []string `json:"h1,omitempty"`
+	H2 []string `json:"h2,omitempty"`
+	H3 []string `json:"h3,omitempty"`
+	// Hreflang lists the <link rel="alternate" hreflang> versions of the page in other languages
+	Hreflang []Alternate `json:"hreflang,omitempty"`
+}
+
+// Alternate is a version of a page for another language or region
+type Alternate struct {
+	Lang string `json:"lang"` // hreflang value, such as en-GB or x-default
+	URL  string `json:"url"`
+}
+
+// extractMetadata reads a parsed page's metadata, resolving URLs against base
+func extractMetadata(doc *goquery.Document, base string) *Metadata {
+	metadata := &Metadata{
+		Lang: strings.TrimSpace(doc.Find("html").AttrOr("lang", "")),
+	}
+
+	// Meta tags are keyed by name, or by property as OpenGraph specifies
+	doc.Find("meta[content]").Each(func(i int, s *goquery.Selection) {
+		key := strings.ToLower(strings.TrimSpace(s.AttrOr("name", s.AttrOr("property", ""))))
+		content := strings.TrimSpace(s.AttrOr("content", ""))
+		switch {
+		case key == "description" && metadata.Description == "":
+			metadata.Description = content
+		case key == "robots" && metadata.Robots == "":
+			metadata.Robots = content
+		case strings.HasPrefix(key, "og:"):
+			metadata.OpenGraph = addProperty(metadata.OpenGraph, key, content)
+		case strings.HasPrefix(key, "twitter:"):
+			metadata.Twitter = addProperty(metadata.Twitter, key, content)
+		}
+	})
+
+	// The canonical URL names the preferred address of a page reachable under several,
+	// and alternates name its translations
+	doc.Find("link[rel][href]").Each(func(i int, s *goquery.Selection) {
+		for _, rel := range strings.Fields(strings.ToLower(s.AttrOr("rel", ""))) {
+			switch rel {
+			case "canonical":
+				if metadata.Canonical == "" {
+					metadata.Canonical = resolveLink(base, s.AttrOr("href", ""))
+				}
+			case "alternate":
+				lang := strings.TrimSpace(s.AttrOr("hreflang", ""))
+				if url := resolveLink(base, s.AttrOr("href", "")); lang != "" && url != "" {
+					metadata.Hreflang = append(metadata.Hreflang, Alternate{Lang: lang, URL: url})
+				}
+			}
+		}
+	})
+
+	metadata.H1 = headings(doc, "h1")
+	metadata.H2 = headings(doc, "h2")
+	metadata.H3 = headings(doc, "h3")
+	return metadata
+}
+
+// addProperty sets a property unless it is already set, creating the map if needed
+func addProperty(properties map[string]string, key, content string) map[string]string {
+	if properties == nil {
+		properties = make(map[string]string)
+	}
+	if _, ok := properties[key]; !ok {
+		properties[key] = content
+	}
+	return properties
+}
+
+// headings returns the whitespace-collapsed text of the non-empty headings matching selector
+func headings(doc *goquery.Document, selector string) []string {
+	var texts []string
+	doc.Find(selector).Each(func(i int, s *goquery.Selection) {
+		if text := strings.Join(strings.Fields(s.Text()), " "); text != "" {
+			texts = append(texts, text)
+		}
+	})
+	return texts
+}
+
+// resolveLink returns href resolved against base and normalized, or "" if it is not a valid URL
+func resolveLink(base, href string) string {
+	absoluteURL, err := ResolveURL(base, strings.TrimSpace(href))
+	if err != nil {
+		return ""
+	}
+	normalizedURL, err := NormalizeURL(absoluteURL)
+	if err != nil || !IsURLValid(normalizedURL) {
+		return ""
+	}
+	return normalizedURL
+}
@@ -0,0 +1,252 @@
+package crawler
+
+import (
+	"container/heap"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Crawl ordering strategies
+const (
+	OrderFIFO       = "fifo"        // In discovery order, which is roughly breadth-first
+	OrderBFS        = "bfs"         // Strictly by increasing depth
+	OrderDFS        = "dfs"         // Deepest and most recently discovered first
+	OrderBestFirst  = "best-first"  // Highest score first
+	OrderRoundRobin = "round-robin" // One job per host in turn
+)
+
+// ScoreFunc ranks a job for best-first crawling; higher scores are crawled first
+type ScoreFunc func(j Job) float64
+
+// Ordering decides which queued job is crawled next
+type Ordering struct {
+	Strategy string    // One of the Order constants, defaults to OrderFIFO
+	Score    ScoreFunc // Ranks jobs for OrderBestFirst, defaults to DefaultScore
+}
+
+// ParseOrderStrategy checks an ordering strategy name
+func ParseOrderStrategy(name string) (string, error) {
+	switch strings.ToLower(name) {
+	case "", OrderFIFO:
+		return OrderFIFO, nil
+	case OrderBFS, OrderDFS, OrderBestFirst, OrderRoundRobin:
+		return strings.ToLower(name), nil
+	}
+	return "", fmt.Errorf("unknown crawl order %q", name)
+}
+
+// DefaultScore favours URLs with a high sitemap priority and many links pointing to them
+func DefaultScore(j Job) float64 {
+	return j.Priority + math.Log1p(float64(j.Inlinks))
+}
+
+// Boost raises the score of URLs matching a rule
+type Boost struct {
+	Rule   FilterRule
+	Weight float64
+}
+
+// ParseBoost parses a boost written as "<rule>=<weight>", where the rule uses the
+// syntax of ParseFilterRule, e.g. "glob:**/docs/**=2" or "prefix:/old/=-1"
+func ParseBoost(spec string) (Boost, error) {
+	i := strings.LastIndex(spec, "=")
+	if i < 0 {
+		return Boost{}, fmt.Errorf("boost %q is missing a weight", spec)
+	}
+	weight, err := strconv.ParseFloat(spec[i+1:], 64)
+	if err != nil {
+		return Boost{}, fmt.Errorf("invalid boost weight in %q: %w", spec, err)
+	}
+	rule, err := ParseFilterRule(spec[:i])
+	if err != nil {
+		return Boost{}, err
+	}
+	return Boost{Rule: rule, Weight: weight}, nil
+}
+
+// BoostedScore adds the weight of every matching boost to a base score
+func BoostedScore(base ScoreFunc, boosts []Boost) ScoreFunc {
+	if base == nil {
+		base = DefaultScore
+	}
+	return func(j Job) float64 {
+		score := base(j)
+		for _, b := range boosts {
+			if b.Rule.Match(j.URL) {
+				score += b.Weight
+			}
+		}
+		return score
+	}
+}
+
+// jobQueue is the in-memory part of a frontier. Callers must hold the frontier's lock.
+type jobQueue interface {
+	push(j Job)
+	pop() (Job, bool)
+	len() int
+	// addInlink records another link to a queued URL
+	addInlink(url string)
+}
+
+// newJobQueue creates the queue for an ordering
+func newJobQueue(o Ordering) jobQueue {
+	if o.Strategy == "" || o.Strategy == OrderFIFO {
+		return &fifoQueue{}
+	}
+	if o.Score == nil {
+		o.Score = DefaultScore
+	}
+	return &priorityQueue{
+		ordering: o,
+		byURL:    make(map[string]*queueItem),
+		rounds:   make(map[string]int),
+	}
+}
+
+// fifoQueue returns jobs in the order they were pushed
+type fifoQueue struct {
+	jobs []Job
+}
+
+func (q *fifoQueue) push(j Job) {
+	q.jobs = append(q.jobs, j)
+}
+
+func (q *fifoQueue) pop() (Job, bool) {
+	if len(q.jobs) == 0 {
+		return Job{}, false
+	}
+	j := q.jobs[0]
+	q.jobs[0] = Job{}
+	q.jobs = q.jobs[1:]
+	return j, true
+}
+
+func (q *fifoQueue) len() int {
+	return len(q.jobs)
+}
+
+func (q *fifoQueue) addInlink(string) {}
+
+// queueItem is a job in a priorityQueue
+type queueItem struct {
+	job   Job
+	seq   uint64  // Push order, breaks ties
+	score float64 // For best-first
+	round int     // For round-robin
+	index int     // Position in the heap
+}
+
+// priorityQueue is a heap ordered by one of the strategies
+type priorityQueue struct {
+	ordering  Ordering
+	items     []*queueItem
+	byURL     map[string]*queueItem
+	seq       uint64
+	rounds    map[string]int // Next round per host, for round-robin
+	lastRound int            // Round of the last popped job
+}
+
+func (q *priorityQueue) push(j Job) {
+	q.seq++
+	item := &queueItem{job: j, seq: q.seq}
+	switch q.ordering.Strategy {
+	case OrderBestFirst:
+		item.score = q.ordering.Score(j)
+	case OrderRoundRobin:
+		// A host that had nothing queued joins the current round instead of catching up
+		host := HostOf(j.URL)
+		item.round = q.rounds[host]
+		if item.round < q.lastRound {
+			item.round = q.lastRound
+		}
+		q.rounds[host] = item.round + 1
+	}
+	q.byURL[j.URL] = item
+	heap.Push(q, item)
+}
+
+func (q *priorityQueue) pop() (Job, bool) {
+	if len(q.items) == 0 {
+		return Job{}, false
+	}
+	item := heap.Pop(q).(*queueItem)
+	delete(q.byURL, item.job.URL)
+	q.lastRound = item.round
+	return item.job, true
+}
+
+func (q *priorityQueue) len() int {
+	return len(q.items)
+}
+
+func (q *priorityQueue) addInlink(url string) {
+	item, ok := q.byURL[url]
+	if !ok {
+		return
+	}
+	item.job.Inlinks++
+	if q.ordering.Strategy == OrderBestFirst {
+		item.score = q.ordering.Score(item.job)
+		heap.Fix(q, item.index)
+	}
+}
+
+// Len implements heap.Interface
+func (q *priorityQueue) Len() int {
+	return len(q.items)
+}
+
+// Less implements heap.Interface
+func (q *priorityQueue) Less(i, j int) bool {
+	a, b := q.items[i], q.items[j]
+	switch q.ordering.Strategy {
+	case OrderBFS:
+		if a.job.Depth != b.job.Depth {
+			return a.job.Depth < b.job.Depth
+		}
+	case OrderDFS:
+		if a.job.Depth != b.job.Depth {
+			return a.job.Depth > b.job.Depth
+		}
+		return a.seq > b.seq
+	case OrderBestFirst:
+		if a.score != b.score {
+			return a.score > b.score
+		}
+		if a.job.Depth != b.job.Depth {
+			return a.job.Depth < b.job.Depth
+		}
+	case OrderRoundRobin:
+		if a.round != b.round {
+			return a.round < b.round
+		}
+	}
+	return a.seq < b.seq
+}
+
+// Swap implements heap.Interface
+func (q *priorityQueue) Swap(i, j int) {
+	q.items[i], q.items[j] = q.items[j], q.items[i]
+	q.items[i].index = i
+	q.items[j].index = j
+}
+
+// Push implements heap.Interface
+func (q *priorityQueue) Push(x interface{}) {
+	item := x.(*queueItem)
+	item.index = len(q.items)
+	q.items = append(q.items, item)
+}
+
+// Pop implements heap.Interface
+func (q *priorityQueue) Pop() interface{} {
+	n := len(q.items)
+	item := q.items[n-1]
+	q.items[n-1] = nil
+	q.items = q.items[:n-1]
+	return item
+}
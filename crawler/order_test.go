@@ -0,0 +1,132 @@
+package crawler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPriorityQueueOrder(t *testing.T) {
+	// Each step pushes a job, adds an inlink or pops a job; the jobs left are popped at the end
+	type step struct {
+		push   Job
+		inlink string // Adds a link to a queued URL instead of pushing
+		pop    bool   // Pops one job instead of pushing
+	}
+	job := func(url string, depth int) step { return step{push: Job{URL: url, Depth: depth}} }
+
+	tests := []struct {
+		name     string
+		ordering Ordering
+		steps    []step
+		want     []string
+	}{
+		{
+			name:     "fifo",
+			ordering: Ordering{Strategy: OrderFIFO},
+			steps:    []step{job("https://a/2", 2), job("https://a/1", 1), job("https://a/0", 0)},
+			want:     []string{"https://a/2", "https://a/1", "https://a/0"},
+		},
+		{
+			name:     "bfs by depth, then push order",
+			ordering: Ordering{Strategy: OrderBFS},
+			steps:    []step{job("https://a/2", 2), job("https://a/1a", 1), job("https://a/0", 0), job("https://a/1b", 1)},
+			want:     []string{"https://a/0", "https://a/1a", "https://a/1b", "https://a/2"},
+		},
+		{
+			name:     "dfs deepest and newest first",
+			ordering: Ordering{Strategy: OrderDFS},
+			steps:    []step{job("https://a/1a", 1), job("https://a/2", 2), job("https://a/1b", 1), job("https://a/0", 0)},
+			want:     []string{"https://a/2", "https://a/1b", "https://a/1a", "https://a/0"},
+		},
+		{
+			name:     "best-first by sitemap priority, then depth",
+			ordering: Ordering{Strategy: OrderBestFirst},
+			steps: []step{
+				{push: Job{URL: "https://a/low", Priority: 0.1}},
+				{push: Job{URL: "https://a/deep", Priority: 0.5, Depth: 2}},
+				{push: Job{URL: "https://a/high", Priority: 0.9}},
+				{push: Job{URL: "https://a/shallow", Priority: 0.5, Depth: 1}},
+			},
+			want: []string{"https://a/high", "https://a/shallow", "https://a/deep", "https://a/low"},
+		},
+		{
+			name:     "best-first moves linked URLs up",
+			ordering: Ordering{Strategy: OrderBestFirst},
+			steps: []step{
+				{push: Job{URL: "https://a/1", Inlinks: 1}},
+				{push: Job{URL: "https://a/2", Inlinks: 1}},
+				{push: Job{URL: "https://a/3", Inlinks: 1}},
+				{inlink: "https://a/3"},
+				{inlink: "https://a/unknown"},
+			},
+			want: []string{"https://a/3", "https://a/1", "https://a/2"},
+		},
+		{
+			name: "best-first with boosts",
+			ordering: Ordering{Strategy: OrderBestFirst, Score: BoostedScore(nil, []Boost{
+				{Rule: mustParseFilterRule(t, "glob:**/docs/**"), Weight: 2},
+				{Rule: mustParseFilterRule(t, "prefix:/archive/"), Weight: -1},
+			})},
+			steps: []step{job("https://a/archive/old", 0), job("https://a/blog/post", 0), job("https://a/docs/intro", 0)},
+			want:  []string{"https://a/docs/intro", "https://a/blog/post", "https://a/archive/old"},
+		},
+		{
+			name:     "round-robin one job per host in turn",
+			ordering: Ordering{Strategy: OrderRoundRobin},
+			steps: []step{
+				job("https://a/1", 0), job("https://a/2", 0), job("https://a/3", 0),
+				job("https://b/1", 0), job("https://b/2", 0), job("https://c/1", 0),
+			},
+			want: []string{"https://a/1", "https://b/1", "https://c/1", "https://a/2", "https://b/2", "https://a/3"},
+		},
+		{
+			name:     "round-robin late host joins the current round",
+			ordering: Ordering{Strategy: OrderRoundRobin},
+			steps: []step{
+				job("https://a/1", 0), job("https://a/2", 0), job("https://a/3", 0),
+				{pop: true}, {pop: true},
+				job("https://b/1", 0), job("https://b/2", 0),
+			},
+			want: []string{"https://a/1", "https://a/2", "https://b/1", "https://a/3", "https://b/2"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newJobQueue(tt.ordering)
+			var got []string
+			for _, s := range tt.steps {
+				switch {
+				case s.pop:
+					j, ok := q.pop()
+					if !ok {
+						t.Fatal("pop from an empty queue")
+					}
+					got = append(got, j.URL)
+				case s.inlink != "":
+					q.addInlink(s.inlink)
+				default:
+					q.push(s.push)
+				}
+			}
+			for q.len() > 0 {
+				j, _ := q.pop()
+				got = append(got, j.URL)
+			}
+			if _, ok := q.pop(); ok {
+				t.Error("pop from an empty queue succeeded")
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func mustParseFilterRule(t *testing.T, spec string) FilterRule {
+	t.Helper()
+	rule, err := ParseFilterRule(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rule
+}
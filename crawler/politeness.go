@@ -0,0 +1,256 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// latencySmoothing is the weight of the newest sample in the latency average
+const latencySmoothing = 0.3
+
+// hostState tracks the politeness state of a single host
+type hostState struct {
+	minDelay time.Duration // Lower bound from config and Crawl-delay
+	delay    time.Duration // Current delay between requests
+	latency  time.Duration // Smoothed response latency
+	next     time.Time     // Earliest start of the next request
+	active   int           // Requests currently in flight
+	requests int           // Responses observed so far
+	released chan struct{} // Closed whenever a connection slot is freed
+}
+
+// HostStats is a snapshot of the politeness state of a single host
+type HostStats struct {
+	Delay    time.Duration `json:"delay"`
+	Latency  time.Duration `json:"latency"`
+	Active   int           `json:"active"`
+	Requests int           `json:"requests"`
+}
+
+// hostScheduler enforces a minimum delay and a connection cap per host.
+// With adaptive throttling it also adjusts each host's delay to its latency and overload responses.
+type hostScheduler struct {
+	mu       sync.Mutex
+	hosts    map[string]*hostState
+	delay    time.Duration
+	maxDelay time.Duration
+	maxConns int
+	adaptive bool
+	logger   *log.Logger
+}
+
+// newHostScheduler creates a scheduler with the given per-host defaults
+func newHostScheduler(delay, maxDelay time.Duration, maxConns int, adaptive bool, logger *log.Logger) *hostScheduler {
+	return &hostScheduler{
+		hosts:    make(map[string]*hostState),
+		delay:    delay,
+		maxDelay: maxDelay,
+		maxConns: maxConns,
+		adaptive: adaptive,
+		logger:   logger,
+	}
+}
+
+// state returns the state of a host, creating it if needed. Callers must hold mu.
+func (s *hostScheduler) state(host string) *hostState {
+	st, ok := s.hosts[host]
+	if !ok {
+		st = &hostState{
+			minDelay: s.delay,
+			delay:    s.delay,
+			released: make(chan struct{}),
+		}
+		s.hosts[host] = st
+	}
+	return st
+}
+
+// setCrawlDelay raises the host's delay to a robots.txt Crawl-delay
+func (s *hostScheduler) setCrawlDelay(host string, delay time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.state(host)
+	if delay > st.minDelay {
+		st.minDelay = delay
+	}
+	if st.minDelay > st.delay {
+		st.delay = st.minDelay
+	}
+}
+
+// tryAcquire takes a request slot for the host if one is available right now
+func (s *hostScheduler) tryAcquire(host string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.take(s.state(host), time.Now())
+}
+
+// take claims a slot if the host is ready. Callers must hold mu.
+func (s *hostScheduler) take(st *hostState, now time.Time) bool {
+	if st.active >= s.maxConns || now.Before(st.next) {
+		return false
+	}
+	st.active++
+	st.next = now.Add(st.delay)
+	return true
+}
+
+// acquire blocks until the host has a free slot and its delay has passed.
+// It returns false if the context is cancelled while waiting.
+func (s *hostScheduler) acquire(ctx context.Context, host string) bool {
+	for {
+		s.mu.Lock()
+		st := s.state(host)
+		now := time.Now()
+		if s.take(st, now) {
+			s.mu.Unlock()
+			return true
+		}
+
+		// Wait for the delay to pass or for a slot to be released
+		var timer *time.Timer
+		var timerC <-chan time.Time
+		if st.active < s.maxConns {
+			timer = time.NewTimer(st.next.Sub(now))
+			timerC = timer.C
+		}
+		released := st.released
+		s.mu.Unlock()
+
+		select {
+		case <-timerC:
+		case <-released:
+		case <-ctx.Done():
+			if timer != nil {
+				timer.Stop()
+			}
+			return false
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// release frees the slot taken by acquire or tryAcquire
+func (s *hostScheduler) release(host string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.state(host)
+	st.active--
+	close(st.released)
+	st.released = make(chan struct{})
+}
+
+// observe feeds a response into the throttle. A non-nil err means the request failed without a response.
+func (s *hostScheduler) observe(host string, latency time.Duration, status int, retryAfter time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	st := s.state(host)
+	st.requests++
+
+	// Failures such as DNS or TLS errors say nothing about the host's load
+	if err != nil && !isOverloadError(err) {
+		return
+	}
+	overloaded := err != nil || status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable
+
+	// Retry-After pauses the host regardless of adaptive throttling
+	if retryAfter > 0 {
+		if retryAfter > s.maxDelay {
+			retryAfter = s.maxDelay
+		}
+		if until := time.Now().Add(retryAfter); until.After(st.next) {
+			st.next = until
+		}
+		s.logger.Printf("Throttle: %s asked to retry after %s (status %d)", host, retryAfter, status)
+	}
+
+	if !s.adaptive {
+		return
+	}
+
+	previous := st.delay
+	if overloaded {
+		// Back off exponentially while the host is struggling
+		st.delay *= 2
+		if st.delay < time.Second {
+			st.delay = time.Second
+		}
+	} else {
+		// Track latency and move the delay towards what the host can sustain
+		if st.latency == 0 {
+			st.latency = latency
+		} else {
+			st.latency = time.Duration(latencySmoothing*float64(latency) + (1-latencySmoothing)*float64(st.latency))
+		}
+		target := st.latency / time.Duration(s.maxConns)
+		newDelay := (st.delay + target) / 2
+
+		// Never speed up on server errors
+		if status < 500 || newDelay > st.delay {
+			st.delay = newDelay
+		}
+	}
+
+	// Keep the delay within its bounds
+	if st.delay < st.minDelay {
+		st.delay = st.minDelay
+	}
+	if st.delay > s.maxDelay {
+		st.delay = s.maxDelay
+	}
+
+	if changed := st.delay - previous; changed > previous/4 || -changed > previous/4 {
+		s.logger.Printf("Throttle: %s delay %s -> %s (status %d, latency %s)", host, previous, st.delay, status, st.latency)
+	}
+}
+
+// isOverloadError reports whether a failed request suggests the host is struggling: it timed out
+// or the host reset the connection
+func isOverloadError(err error) bool {
+	return ClassifyError(0, err) == ErrorTimeout || errors.Is(err, syscall.ECONNRESET)
+}
+
+// stats returns a snapshot of every host's throttle state
+func (s *hostScheduler) stats() map[string]HostStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := make(map[string]HostStats, len(s.hosts))
+	for host, st := range s.hosts {
+		stats[host] = HostStats{
+			Delay:    st.delay,
+			Latency:  st.latency,
+			Active:   st.active,
+			Requests: st.requests,
+		}
+	}
+	return stats
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if wait := time.Until(t); wait > 0 {
+			return wait
+		}
+	}
+	return 0
+}
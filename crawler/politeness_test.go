@@ -0,0 +1,46 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestHostSchedulerObserveBackoff(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    error
+		backed bool
+	}{
+		{"too many requests", http.StatusTooManyRequests, nil, true},
+		{"service unavailable", http.StatusServiceUnavailable, nil, true},
+		{"timeout", 0, &url.Error{Op: "Get", URL: "http://a/", Err: context.DeadlineExceeded}, true},
+		{"connection reset", 0, &url.Error{Op: "Get", URL: "http://a/", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}, true},
+		{"dns failure", 0, &url.Error{Op: "Get", URL: "http://a/", Err: &net.DNSError{Err: "no such host", Name: "a"}}, false},
+		{"connection refused", 0, &url.Error{Op: "Get", URL: "http://a/", Err: &net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}}, false},
+		{"tls failure", 0, &url.Error{Op: "Get", URL: "http://a/", Err: errors.New("tls: handshake failure")}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newHostScheduler(100*time.Millisecond, 30*time.Second, 1, true, log.New(io.Discard, "", 0))
+			s.observe("a", 50*time.Millisecond, tt.status, 0, tt.err)
+
+			delay := s.stats()["a"].Delay
+			if tt.backed && delay < time.Second {
+				t.Errorf("delay = %s, want a back-off to at least 1s", delay)
+			}
+			if !tt.backed && delay != 100*time.Millisecond {
+				t.Errorf("delay = %s, want it unchanged at 100ms", delay)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// Redirect is one hop of a redirect chain
+type Redirect struct {
+	URL        string `json:"url"`         // URL that was redirected
+	StatusCode int    `json:"status_code"` // Redirect status, such as 301 or 302
+	Location   string `json:"location"`    // Resolved URL the response pointed to
+}
+
+// errBlockedByRobots stops a redirect into a URL that robots.txt disallows
+var errBlockedByRobots = errors.New("disallowed by robots.txt")
+
+// redirectChain collects the hops of a request while the client follows them
+type redirectChain struct {
+	mu   sync.Mutex
+	hops []Redirect
+}
+
+// redirectChainKey is the context key of a request's redirect chain
+type redirectChainKey struct{}
+
+// trackRedirects attaches a redirect chain to a request, filled in by checkRedirect
+func trackRedirects(req *http.Request) (*http.Request, *redirectChain) {
+	chain := &redirectChain{}
+	return req.WithContext(context.WithValue(req.Context(), redirectChainKey{}, chain)), chain
+}
+
+// list returns the hops recorded so far
+func (rc *redirectChain) list() []Redirect {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	return append([]Redirect(nil), rc.hops...)
+}
+
+// checkRedirect is the client's CheckRedirect hook. It records each hop and stops at
+// redirect loops, at chains longer than Config.MaxRedirects and, when respecting robots.txt,
+// at redirects into disallowed URLs.
+func (c *Crawler) checkRedirect(req *http.Request, via []*http.Request) error {
+	hop := Redirect{
+		URL:      via[len(via)-1].URL.String(),
+		Location: req.URL.String(),
+	}
+	if req.Response != nil {
+		hop.StatusCode = req.Response.StatusCode
+		if c.config.Archiver != nil {
+			c.archiveRedirect(req.Response)
+		}
+	}
+	chain, tracked := req.Context().Value(redirectChainKey{}).(*redirectChain)
+	if tracked {
+		chain.mu.Lock()
+		chain.hops = append(chain.hops, hop)
+		chain.mu.Unlock()
+	}
+
+	for _, previous := range via {
+		if previous.URL.String() == hop.Location {
+			return &categorizedError{ErrorRedirectLoop, fmt.Errorf("redirect loop back to %s", hop.Location)}
+		}
+	}
+	if len(via) > c.config.MaxRedirects {
+		return &categorizedError{ErrorTooManyRedirects, fmt.Errorf("stopped after %d redirects", c.config.MaxRedirects)}
+	}
+
+	// Only page requests are checked; robots.txt requests themselves are not tracked
+	if c.config.RespectRobots && tracked {
+		allowed, _, err := c.robots.allowed(req.Context(), hop.Location)
+		if err != nil {
+			return err
+		}
+		if !allowed {
+			return fmt.Errorf("redirect to %s %w", hop.Location, errBlockedByRobots)
+		}
+	}
+	return nil
+}
+
+// setRedirects records a request's redirect chain and final URL on its result
+func setRedirects(result *Result, chain *redirectChain) {
+	result.Redirects = chain.list()
+	if len(result.Redirects) == 0 {
+		return
+	}
+	final, err := NormalizeURL(result.Redirects[len(result.Redirects)-1].Location)
+	if err != nil {
+		final = result.Redirects[len(result.Redirects)-1].Location
+	}
+	if final != result.URL {
+		result.FinalURL = final
+	}
+}
@@ -0,0 +1,106 @@
+package crawler
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// resultLogFile is the name of the results log inside Config.StateDir
+const resultLogFile = "results.jsonl"
+
+// resultLog appends every result to a JSON Lines file in Config.StateDir. Checkpoints record how
+// far the log went, so a resumed crawl restores its results without keeping them in memory.
+type resultLog struct {
+	file   *os.File
+	writer *bufio.Writer
+	size   int64 // Bytes appended, including those not flushed yet
+}
+
+// openResultLog opens the results log in dir, keeping its first size bytes: the results a
+// checkpoint knows about. Results logged after the checkpoint belong to jobs that are still queued.
+func openResultLog(dir string, size int64) (*resultLog, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create state directory: %w", err)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, resultLogFile), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open results log: %w", err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open results log: %w", err)
+	}
+	if info.Size() < size {
+		file.Close()
+		return nil, fmt.Errorf("results log has %d bytes, the checkpoint expects %d", info.Size(), size)
+	}
+	if err := file.Truncate(size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to truncate results log: %w", err)
+	}
+	if _, err := file.Seek(size, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to open results log: %w", err)
+	}
+
+	return &resultLog{
+		file:   file,
+		writer: bufio.NewWriter(file),
+		size:   size,
+	}, nil
+}
+
+// append adds an encoded result to the log
+func (l *resultLog) append(line []byte) error {
+	n, err := l.writer.Write(append(line, '\n'))
+	l.size += int64(n)
+	return err
+}
+
+// flush writes the buffered results to the file and returns the size of the log
+func (l *resultLog) flush() (int64, error) {
+	return l.size, l.writer.Flush()
+}
+
+// sync commits the flushed results to disk
+func (l *resultLog) sync() error {
+	return l.file.Sync()
+}
+
+// replay calls fn for each result in the log, in the order they were logged
+func (l *resultLog) replay(fn func(result Result) error) error {
+	if _, err := l.flush(); err != nil {
+		return fmt.Errorf("failed to write results log: %w", err)
+	}
+
+	decoder := json.NewDecoder(io.NewSectionReader(l.file, 0, l.size))
+	for {
+		var result Result
+		err := decoder.Decode(&result)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read results log: %w", err)
+		}
+		if err := fn(result); err != nil {
+			return err
+		}
+	}
+}
+
+// close flushes the log and closes the file
+func (l *resultLog) close() error {
+	if _, err := l.flush(); err != nil {
+		l.file.Close()
+		return fmt.Errorf("failed to write results log: %w", err)
+	}
+	return l.file.Close()
+}
@@ -0,0 +1,122 @@
+package crawler
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// logResults appends results to a results log and returns its size afterwards
+func logResults(t *testing.T, log *resultLog, urls ...string) int64 {
+	t.Helper()
+	for _, url := range urls {
+		line, err := json.Marshal(Result{URL: url, StatusCode: 200})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := log.append(line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	size, err := log.flush()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return size
+}
+
+// replayedURLs returns the URLs of the results in a log
+func replayedURLs(t *testing.T, log *resultLog) []string {
+	t.Helper()
+	var urls []string
+	if err := log.replay(func(result Result) error {
+		urls = append(urls, result.URL)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return urls
+}
+
+func TestResultLogReopen(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra string // Written after the checkpointed results, as a crash could leave them
+	}{
+		{"nothing after the checkpoint", ""},
+		{"complete results after the checkpoint", `{"url":"http://a/3","status_code":200}` + "\n"},
+		{"truncated final line", `{"url":"http://a/3","sta`},
+		{"complete and truncated lines", `{"url":"http://a/3","status_code":200}` + "\n" + `{"url":"http://a/4"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			log, err := openResultLog(dir, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkpointed := logResults(t, log, "http://a/1", "http://a/2")
+			if err := log.close(); err != nil {
+				t.Fatal(err)
+			}
+
+			file, err := os.OpenFile(filepath.Join(dir, resultLogFile), os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.WriteString(tt.extra)
+			file.Close()
+
+			// Reopening at the checkpoint drops whatever came after it
+			log, err = openResultLog(dir, checkpointed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer log.close()
+			if got := strings.Join(replayedURLs(t, log), " "); got != "http://a/1 http://a/2" {
+				t.Errorf("replayed %s, want the two checkpointed results", got)
+			}
+
+			// New results follow on from the checkpointed ones
+			size := logResults(t, log, "http://a/5")
+			if got := strings.Join(replayedURLs(t, log), " "); got != "http://a/1 http://a/2 http://a/5" {
+				t.Errorf("replayed %s after appending", got)
+			}
+			if info, err := os.Stat(filepath.Join(dir, resultLogFile)); err != nil || info.Size() != size {
+				t.Errorf("log file has %v bytes, want %d", info.Size(), size)
+			}
+		})
+	}
+}
+
+func TestResultLogErrors(t *testing.T) {
+	dir := t.TempDir()
+	log, err := openResultLog(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := logResults(t, log, "http://a/1")
+	if err := log.close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A checkpoint that expects more results than were logged cannot be resumed
+	if _, err := openResultLog(dir, size+1); err == nil {
+		t.Error("opening a log shorter than the checkpoint succeeded")
+	}
+
+	// A line cut off within the checkpointed part is corrupt, not silently skipped
+	if err := os.WriteFile(filepath.Join(dir, resultLogFile), []byte(`{"url":"http://a/1"`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	log, err = openResultLog(dir, int64(len(`{"url":"http://a/1"`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer log.close()
+	if err := log.replay(func(Result) error { return nil }); err == nil {
+		t.Error("replaying a truncated line succeeded")
+	}
+}
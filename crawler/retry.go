@@ -0,0 +1,138 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how transient failures are retried
+type RetryPolicy struct {
+	MaxAttempts int           // Total attempts per URL, including the first; 1 disables retries
+	BaseBackoff time.Duration // Delay before the first retry, doubled for each further attempt
+	MaxBackoff  time.Duration // Upper bound for the delay between attempts
+	Jitter      float64       // Fraction of each delay that is randomised, between 0 and 1
+	// RetryableStatusCodes lists the HTTP status codes worth retrying
+	RetryableStatusCodes []int
+	// IsRetryableError decides whether a request error (no response received) is worth retrying
+	IsRetryableError func(error) bool
+}
+
+// DefaultRetryableStatusCodes are retried unless RetryPolicy.RetryableStatusCodes is set
+var DefaultRetryableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+// withDefaults fills in unset fields of the policy
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.BaseBackoff <= 0 {
+		p.BaseBackoff = 500 * time.Millisecond
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = 30 * time.Second
+	}
+	if p.Jitter < 0 {
+		p.Jitter = 0
+	}
+	if p.Jitter > 1 {
+		p.Jitter = 1
+	}
+	if p.RetryableStatusCodes == nil {
+		p.RetryableStatusCodes = DefaultRetryableStatusCodes
+	}
+	if p.IsRetryableError == nil {
+		p.IsRetryableError = IsTransientError
+	}
+	return p
+}
+
+// shouldRetry reports whether a failed attempt should be tried again
+func (p RetryPolicy) shouldRetry(statusCode int, err error, attempts int) bool {
+	if attempts >= p.MaxAttempts {
+		return false
+	}
+
+	// A response was received: decide by status code
+	if statusCode != 0 {
+		for _, code := range p.RetryableStatusCodes {
+			if code == statusCode {
+				return true
+			}
+		}
+		return false
+	}
+
+	return p.IsRetryableError(err)
+}
+
+// backoff returns the delay before the given retry (1 for the first retry)
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	delay := float64(p.BaseBackoff) * math.Pow(2, float64(retry-1))
+	if delay > float64(p.MaxBackoff) {
+		delay = float64(p.MaxBackoff)
+	}
+
+	// Randomise part of the delay so retries from many workers spread out
+	delay -= delay * p.Jitter * rand.Float64()
+
+	return time.Duration(delay)
+}
+
+// IsTransientError reports whether a request error is likely to go away on retry,
+// such as timeouts, refused or reset connections and temporary DNS failures
+func IsTransientError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// scheduleRetry puts a failed job back on the queue after its backoff.
+// The job stays counted as pending while it waits, so the crawl does not finish early.
+func (c *Crawler) scheduleRetry(j Job) {
+	j.Attempt++
+	j.deferred = false
+	c.updateJob(j)
+	delay := c.config.Retry.backoff(j.Attempt)
+	c.config.Logger.Printf("Retrying %s in %s (attempt %d of %d)", j.URL, delay, j.Attempt+1, c.config.Retry.MaxAttempts)
+
+	time.AfterFunc(delay, func() {
+		if c.ctx.Err() != nil {
+			c.decrementPendingJobs()
+			return
+		}
+		if err := c.frontier.Push(j); err != nil {
+			c.config.Logger.Printf("Error queueing retry of %s: %v", j.URL, err)
+			c.decrementPendingJobs()
+		}
+	})
+}
@@ -0,0 +1,171 @@
+package crawler
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryBackoff(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		retry    int
+		min, max time.Duration
+	}{
+		{"first retry", RetryPolicy{BaseBackoff: 100 * time.Millisecond, Jitter: 0}, 1, 100 * time.Millisecond, 100 * time.Millisecond},
+		{"doubles", RetryPolicy{BaseBackoff: 100 * time.Millisecond, Jitter: 0}, 2, 200 * time.Millisecond, 200 * time.Millisecond},
+		{"doubles again", RetryPolicy{BaseBackoff: 100 * time.Millisecond, Jitter: 0}, 4, 800 * time.Millisecond, 800 * time.Millisecond},
+		{"capped", RetryPolicy{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: 0}, 10, time.Second, time.Second},
+		{"capped far out", RetryPolicy{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second, Jitter: 0}, 200, 5 * time.Second, 5 * time.Second},
+		{"half jitter", RetryPolicy{BaseBackoff: time.Second, Jitter: 0.5}, 1, 500 * time.Millisecond, time.Second},
+		{"full jitter", RetryPolicy{BaseBackoff: time.Second, Jitter: 1}, 2, 0, 2 * time.Second},
+		{"jitter after the cap", RetryPolicy{BaseBackoff: time.Second, MaxBackoff: 2 * time.Second, Jitter: 0.25}, 5, 1500 * time.Millisecond, 2 * time.Second},
+		{"jitter above 1 is clamped", RetryPolicy{BaseBackoff: time.Second, Jitter: 3}, 1, 0, time.Second},
+		{"negative jitter is clamped", RetryPolicy{BaseBackoff: time.Second, Jitter: -1}, 1, time.Second, time.Second},
+		{"defaults", RetryPolicy{}, 1, 500 * time.Millisecond, 500 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy.withDefaults()
+			for i := 0; i < 200; i++ {
+				if got := policy.backoff(tt.retry); got < tt.min || got > tt.max {
+					t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.retry, got, tt.min, tt.max)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryPolicyDefaults(t *testing.T) {
+	policy := RetryPolicy{}.withDefaults()
+	if policy.MaxAttempts != 3 || policy.BaseBackoff != 500*time.Millisecond || policy.MaxBackoff != 30*time.Second {
+		t.Errorf("defaults = %d attempts, %s base, %s max", policy.MaxAttempts, policy.BaseBackoff, policy.MaxBackoff)
+	}
+	if policy.Jitter != 0 {
+		t.Errorf("default jitter = %v, want 0 unless set", policy.Jitter)
+	}
+}
+
+func TestShouldRetry(t *testing.T) {
+	timeout := &url.Error{Op: "Get", URL: "http://a/", Err: context.DeadlineExceeded}
+	reset := &url.Error{Op: "Get", URL: "http://a/", Err: &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}}
+	notFound := &url.Error{Op: "Get", URL: "http://a/", Err: &net.DNSError{Err: "no such host", Name: "a", IsNotFound: true}}
+	temporaryDNS := &url.Error{Op: "Get", URL: "http://a/", Err: &net.DNSError{Err: "server misbehaving", Name: "a", IsTemporary: true}}
+	custom := RetryPolicy{
+		MaxAttempts:          5,
+		RetryableStatusCodes: []int{http.StatusNotFound},
+		IsRetryableError:     func(err error) bool { return errors.Is(err, io.ErrClosedPipe) },
+	}.withDefaults()
+
+	tests := []struct {
+		name     string
+		policy   RetryPolicy
+		status   int
+		err      error
+		attempts int
+		want     bool
+	}{
+		{"service unavailable", RetryPolicy{}, http.StatusServiceUnavailable, nil, 1, true},
+		{"too many requests", RetryPolicy{}, http.StatusTooManyRequests, nil, 1, true},
+		{"request timeout", RetryPolicy{}, http.StatusRequestTimeout, nil, 1, true},
+		{"gateway timeout", RetryPolicy{}, http.StatusGatewayTimeout, nil, 2, true},
+		{"attempts used up", RetryPolicy{}, http.StatusServiceUnavailable, nil, 3, false},
+		{"not found", RetryPolicy{}, http.StatusNotFound, nil, 1, false},
+		{"forbidden", RetryPolicy{}, http.StatusForbidden, nil, 1, false},
+		{"not implemented", RetryPolicy{}, http.StatusNotImplemented, nil, 1, false},
+		{"status wins over error", RetryPolicy{}, http.StatusNotFound, timeout, 1, false},
+		{"timeout", RetryPolicy{}, 0, timeout, 1, true},
+		{"connection reset", RetryPolicy{}, 0, reset, 1, true},
+		{"unexpected EOF", RetryPolicy{}, 0, io.ErrUnexpectedEOF, 1, true},
+		{"unknown host", RetryPolicy{}, 0, notFound, 1, false},
+		{"temporary DNS failure", RetryPolicy{}, 0, temporaryDNS, 1, true},
+		{"cancelled", RetryPolicy{}, 0, context.Canceled, 1, false},
+		{"other error", RetryPolicy{}, 0, errors.New("boom"), 1, false},
+		{"retries disabled", RetryPolicy{MaxAttempts: 1}, http.StatusServiceUnavailable, nil, 1, false},
+		{"custom status", custom, http.StatusNotFound, nil, 4, true},
+		{"custom status replaces the defaults", custom, http.StatusServiceUnavailable, nil, 1, false},
+		{"custom error check", custom, 0, io.ErrClosedPipe, 1, true},
+		{"custom error check replaces the default", custom, 0, timeout, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := tt.policy.withDefaults()
+			if got := policy.shouldRetry(tt.status, tt.err, tt.attempts); got != tt.want {
+				t.Errorf("shouldRetry(%d, %v, %d) = %v, want %v", tt.status, tt.err, tt.attempts, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		header   string
+		min, max time.Duration
+	}{
+		{"seconds", http.StatusTooManyRequests, "5", 5 * time.Second, 5 * time.Second},
+		{"padded seconds", http.StatusServiceUnavailable, " 2 ", 2 * time.Second, 2 * time.Second},
+		{"HTTP date", http.StatusServiceUnavailable, time.Now().Add(10 * time.Second).UTC().Format(http.TimeFormat), 8 * time.Second, 10 * time.Second},
+		{"date in the past", http.StatusTooManyRequests, time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat), 0, 0},
+		{"negative", http.StatusTooManyRequests, "-3", 0, 0},
+		{"malformed", http.StatusTooManyRequests, "soon", 0, 0},
+		{"missing", http.StatusTooManyRequests, "", 0, 0},
+		{"not an overload response", http.StatusInternalServerError, "5", 0, 0},
+		{"success", http.StatusOK, "5", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			if tt.header != "" {
+				resp.Header.Set("Retry-After", tt.header)
+			}
+			if got := retryAfter(resp); got < tt.min || got > tt.max {
+				t.Errorf("retryAfter() = %s, want between %s and %s", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRetryAfterPausesHost(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter []time.Duration // Observed in order
+		adaptive   bool
+		wantPause  time.Duration // Approximate time until the host may be requested again
+	}{
+		{"pause", []time.Duration{time.Second}, false, time.Second},
+		{"pause with adaptive throttling", []time.Duration{time.Second}, true, time.Second},
+		{"capped at the maximum delay", []time.Duration{time.Hour}, false, 2 * time.Second},
+		{"a shorter pause does not cut a longer one", []time.Duration{time.Second, 10 * time.Millisecond}, false, time.Second},
+		{"a longer pause extends a shorter one", []time.Duration{10 * time.Millisecond, time.Second}, false, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newHostScheduler(time.Millisecond, 2*time.Second, 1, tt.adaptive, log.New(io.Discard, "", 0))
+			for _, pause := range tt.retryAfter {
+				s.observe("a", time.Millisecond, http.StatusServiceUnavailable, pause, nil)
+			}
+
+			// A retried request waits for its host, so Retry-After takes precedence over a shorter backoff
+			s.mu.Lock()
+			pause := time.Until(s.state("a").next)
+			s.mu.Unlock()
+			if pause < tt.wantPause-200*time.Millisecond || pause > tt.wantPause {
+				t.Errorf("host paused for %s, want about %s", pause, tt.wantPause)
+			}
+			if s.tryAcquire("a") {
+				t.Error("host could be requested during its Retry-After pause")
+			}
+		})
+	}
+}
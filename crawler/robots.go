@@ -0,0 +1,335 @@
+package crawler
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxRobotsSize limits how much of a robots.txt file is read (RFC 9309 requires at least 500 KiB)
+const maxRobotsSize = 512 * 1024
+
+// robotsErrorTTL is how long a robots.txt that could not be fetched keeps its host disallowed
+// before it is requested again
+const robotsErrorTTL = 10 * time.Second
+
+// robotsRule is a single Allow or Disallow directive
+type robotsRule struct {
+	allow   bool
+	pattern string
+}
+
+// robotsRules holds the directives of robots.txt that apply to the crawler
+type robotsRules struct {
+	rules      []robotsRule
+	crawlDelay time.Duration
+	sitemaps   []string
+	err        error // Set when robots.txt could not be fetched; everything is disallowed meanwhile
+}
+
+// robotsUnavailableError reports that a host's robots.txt could not be fetched. Pages of the host
+// fail with it, rather than count as blocked, until robots.txt is fetched again.
+type robotsUnavailableError struct {
+	statusCode int // Status of the robots.txt response, or 0 if none was received
+	err        error
+}
+
+// Error implements error
+func (e *robotsUnavailableError) Error() string {
+	return "robots.txt unavailable: " + e.err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *robotsUnavailableError) Unwrap() error {
+	return e.err
+}
+
+// robotsEntry is a cached robots.txt for a single host
+type robotsEntry struct {
+	ready   chan struct{}
+	rules   *robotsRules
+	expires time.Time // When an unavailable robots.txt is fetched again; zero for a loaded one
+}
+
+// expired reports whether the entry has been fetched and should be fetched again
+func (e *robotsEntry) expired() bool {
+	select {
+	case <-e.ready:
+		return !e.expires.IsZero() && time.Now().After(e.expires)
+	default:
+		return false
+	}
+}
+
+// robotsCache fetches, parses and caches robots.txt per host
+type robotsCache struct {
+	client    *http.Client
+	userAgent string
+	logger    *log.Logger
+	mu        sync.Mutex
+	hosts     map[string]*robotsEntry
+}
+
+// newRobotsCache creates an empty robots.txt cache
+func newRobotsCache(client *http.Client, userAgent string, logger *log.Logger) *robotsCache {
+	return &robotsCache{
+		client:    client,
+		userAgent: userAgent,
+		logger:    logger,
+		hosts:     make(map[string]*robotsEntry),
+	}
+}
+
+// allowed reports whether the URL may be crawled and the Crawl-delay of its host.
+// While the host's robots.txt is unavailable it returns a *robotsUnavailableError instead.
+func (r *robotsCache) allowed(ctx context.Context, rawURL string) (bool, time.Duration, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false, 0, nil
+	}
+
+	rules := r.get(ctx, u)
+	if rules.err != nil {
+		return false, 0, rules.err
+	}
+
+	// Match against the path and query, as robots.txt rules do
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	return rules.isAllowed(path), rules.crawlDelay, nil
+}
+
+// get returns the rules for the URL's host, fetching robots.txt on first use
+func (r *robotsCache) get(ctx context.Context, u *url.URL) *robotsRules {
+	key := u.Scheme + "://" + u.Host
+
+	r.mu.Lock()
+	entry, ok := r.hosts[key]
+	if ok && entry.expired() {
+		// Try an unavailable robots.txt again
+		ok = false
+	}
+	if !ok {
+		entry = &robotsEntry{ready: make(chan struct{})}
+		r.hosts[key] = entry
+	}
+	r.mu.Unlock()
+
+	if ok {
+		// Another worker is already fetching or has fetched this host
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return &robotsRules{}
+		}
+		return entry.rules
+	}
+
+	entry.rules = r.fetch(ctx, key+"/robots.txt")
+	if entry.rules.err != nil {
+		entry.expires = time.Now().Add(robotsErrorTTL)
+	}
+	close(entry.ready)
+	return entry.rules
+}
+
+// fetch downloads and parses a robots.txt file
+func (r *robotsCache) fetch(ctx context.Context, robotsURL string) *robotsRules {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return &robotsRules{}
+	}
+	req.Header.Set("User-Agent", r.userAgent)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// An unreachable robots.txt means the whole host is disallowed, for now
+		r.logger.Printf("Error fetching %s, disallowing host for %s: %v", robotsURL, robotsErrorTTL, err)
+		return unavailable(0, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		rules := parseRobots(io.LimitReader(resp.Body, maxRobotsSize), robotsToken(r.userAgent))
+		r.logger.Printf("Loaded %s: %d rules, crawl-delay %s", robotsURL, len(rules.rules), rules.crawlDelay)
+		return rules
+	case resp.StatusCode >= 400 && resp.StatusCode < 500:
+		// No robots.txt, everything is allowed
+		return &robotsRules{}
+	default:
+		r.logger.Printf("Unexpected status %d for %s, disallowing host for %s", resp.StatusCode, robotsURL, robotsErrorTTL)
+		category := ErrorOther
+		if resp.StatusCode >= 500 {
+			category = ErrorHTTP5xx
+		}
+		return unavailable(resp.StatusCode, &categorizedError{category, fmt.Errorf("unexpected status code: %d", resp.StatusCode)})
+	}
+}
+
+// unavailable returns rules that block every path because robots.txt could not be fetched
+func unavailable(statusCode int, err error) *robotsRules {
+	return &robotsRules{
+		rules: []robotsRule{{allow: false, pattern: "/"}},
+		err:   &robotsUnavailableError{statusCode: statusCode, err: err},
+	}
+}
+
+// robotsToken extracts the product token used to match user-agent groups
+func robotsToken(userAgent string) string {
+	token := userAgent
+	if i := strings.IndexAny(token, "/ "); i != -1 {
+		token = token[:i]
+	}
+	return strings.ToLower(token)
+}
+
+// parseRobots parses robots.txt and keeps the group that best matches the agent
+func parseRobots(r io.Reader, agent string) *robotsRules {
+	result := &robotsRules{}
+
+	// Each group records how well it matches: 1 for our agent, 0 for "*", -1 for neither
+	var groups []*robotsRules
+	var matches []int
+	inRules := false
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Strip comments and split the directive
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "user-agent":
+			// A user-agent line after rules starts a new group
+			if inRules || len(groups) == 0 {
+				groups = append(groups, &robotsRules{})
+				matches = append(matches, -1)
+				inRules = false
+			}
+			current := len(groups) - 1
+			name := strings.ToLower(value)
+			if name == agent {
+				matches[current] = 1
+			} else if name == "*" && matches[current] < 0 {
+				matches[current] = 0
+			}
+		case "allow", "disallow":
+			if len(groups) == 0 {
+				continue
+			}
+			inRules = true
+			if value == "" {
+				continue
+			}
+			current := groups[len(groups)-1]
+			current.rules = append(current.rules, robotsRule{allow: key == "allow", pattern: value})
+		case "crawl-delay":
+			if len(groups) == 0 {
+				continue
+			}
+			inRules = true
+			if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+				groups[len(groups)-1].crawlDelay = time.Duration(seconds * float64(time.Second))
+			}
+		case "sitemap":
+			// Sitemap lines are independent of groups
+			if value != "" {
+				result.sitemaps = append(result.sitemaps, value)
+			}
+		}
+	}
+
+	// Use the best matching groups; groups for the same agent are merged
+	best := -1
+	for _, m := range matches {
+		if m > best {
+			best = m
+		}
+	}
+	if best < 0 {
+		return result
+	}
+	for i, group := range groups {
+		if matches[i] != best {
+			continue
+		}
+		result.rules = append(result.rules, group.rules...)
+		if group.crawlDelay > result.crawlDelay {
+			result.crawlDelay = group.crawlDelay
+		}
+	}
+
+	return result
+}
+
+// isAllowed applies the longest matching rule; Allow wins ties
+func (r *robotsRules) isAllowed(path string) bool {
+	allowed := true
+	matchLen := -1
+	for _, rule := range r.rules {
+		if !robotsMatch(rule.pattern, path) {
+			continue
+		}
+		l := len(rule.pattern)
+		if l > matchLen || (l == matchLen && rule.allow) {
+			matchLen = l
+			allowed = rule.allow
+		}
+	}
+	return allowed
+}
+
+// robotsMatch matches a path against a robots.txt pattern with * and $ support
+func robotsMatch(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+
+	// The first part must match the start of the path
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+
+	for i := 1; i < len(parts); i++ {
+		part := parts[i]
+		if i == len(parts)-1 && anchored {
+			// The last part must match the end of the path
+			return len(path)-pos >= len(part) && strings.HasSuffix(path, part)
+		}
+		idx := strings.Index(path[pos:], part)
+		if idx == -1 {
+			return false
+		}
+		pos += idx + len(part)
+	}
+
+	return !anchored || pos == len(path)
+}
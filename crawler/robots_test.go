@@ -0,0 +1,132 @@
+package crawler
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRobotsMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"/", "/anything", true},
+		{"/private", "/private", true},
+		{"/private", "/private/page", true},
+		{"/private", "/privately", true},
+		{"/private", "/public", false},
+		{"/private/", "/private", false},
+		{"/*.php", "/index.php", true},
+		{"/*.php", "/dir/index.php?x=1", true},
+		{"/*.php", "/index.html", false},
+		{"/*.php$", "/index.php", true},
+		{"/*.php$", "/index.php?x=1", false},
+		{"/*.php$", "/php", false},
+		{"/page$", "/page", true},
+		{"/page$", "/page/", false},
+		{"/a*b*c", "/axxbyyc", true},
+		{"/a*b*c", "/axxcyyb", false},
+		{"/a*c$", "/abcbc", true},
+		{"*", "/anything", true},
+		{"/fish*", "/fish", true},
+		{"/fish*", "/Fish", false},
+	}
+	for _, tt := range tests {
+		if got := robotsMatch(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("robotsMatch(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestParseRobots(t *testing.T) {
+	const robots = `# Example robots.txt
+User-agent: *
+Disallow: /private
+Allow: /private/open
+Crawl-delay: 1
+
+User-agent: goCrawler
+User-agent: other
+Disallow: /*.pdf$
+Disallow: /tmp/ # scratch space
+Allow: /tmp/keep
+Crawl-delay: 2.5
+
+User-agent: goCrawler
+Disallow: /admin
+
+Sitemap: https://example.com/sitemap.xml
+`
+
+	tests := []struct {
+		name      string
+		agent     string
+		path      string
+		want      bool
+		wantDelay time.Duration
+	}{
+		{"own group applies", "gocrawler", "/doc.pdf", false, 2500 * time.Millisecond},
+		{"own group anchors $", "gocrawler", "/doc.pdf?download=1", true, 2500 * time.Millisecond},
+		{"own groups are merged", "gocrawler", "/admin/users", false, 2500 * time.Millisecond},
+		{"wildcard group is ignored", "gocrawler", "/private", true, 2500 * time.Millisecond},
+		{"longest match wins", "gocrawler", "/tmp/keep/file", true, 2500 * time.Millisecond},
+		{"shorter disallow", "gocrawler", "/tmp/other", false, 2500 * time.Millisecond},
+		{"shared group", "other", "/doc.pdf", false, 2500 * time.Millisecond},
+		{"shared group without merged rules", "other", "/admin", true, 2500 * time.Millisecond},
+		{"wildcard group", "somebot", "/private/page", false, time.Second},
+		{"wildcard allow", "somebot", "/private/open/page", true, time.Second},
+		{"wildcard unmatched", "somebot", "/doc.pdf", true, time.Second},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots(strings.NewReader(robots), tt.agent)
+			if got := rules.isAllowed(tt.path); got != tt.want {
+				t.Errorf("isAllowed(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+			if rules.crawlDelay != tt.wantDelay {
+				t.Errorf("crawl delay = %s, want %s", rules.crawlDelay, tt.wantDelay)
+			}
+			if len(rules.sitemaps) != 1 || rules.sitemaps[0] != "https://example.com/sitemap.xml" {
+				t.Errorf("sitemaps = %v", rules.sitemaps)
+			}
+		})
+	}
+}
+
+func TestParseRobotsWithoutMatchingGroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		robots string
+	}{
+		{"empty", ""},
+		{"other agents only", "User-agent: otherbot\nDisallow: /\n"},
+		{"rules before any group", "Disallow: /\n"},
+		{"empty disallow", "User-agent: *\nDisallow:\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rules := parseRobots(strings.NewReader(tt.robots), "gocrawler")
+			if !rules.isAllowed("/anything") {
+				t.Errorf("isAllowed(/anything) = false, want true")
+			}
+		})
+	}
+}
+
+func TestRobotsToken(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{DefaultUserAgent, "gocrawler"},
+		{"MyBot", "mybot"},
+		{"MyBot (+https://example.com)", "mybot"},
+	}
+	for _, tt := range tests {
+		if got := robotsToken(tt.userAgent); got != tt.want {
+			t.Errorf("robotsToken(%q) = %q, want %q", tt.userAgent, got, tt.want)
+		}
+	}
+}
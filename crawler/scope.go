@@ -0,0 +1,39 @@
+package crawler
+
+import (
+	"net/url"
+	"strings"
+)
+
+// inScope reports whether a link may be followed from a crawl rooted at the given seed host.
+// Links stay in scope on the seed's own host or on any of Config.AllowedDomains.
+func (c *Crawler) inScope(link, seedHost string) bool {
+	linkURL, err := url.Parse(link)
+	if err != nil {
+		return false
+	}
+	if linkURL.Host == seedHost {
+		return true
+	}
+
+	hostname := strings.ToLower(linkURL.Hostname())
+	for _, pattern := range c.config.AllowedDomains {
+		if MatchDomain(pattern, hostname) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDomain reports whether a hostname matches a domain pattern.
+// A pattern of the form "*.example.com" matches any subdomain of example.com;
+// any other pattern must match the hostname exactly.
+func MatchDomain(pattern, hostname string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	hostname = strings.ToLower(hostname)
+
+	if suffix := strings.TrimPrefix(pattern, "*"); suffix != pattern {
+		return strings.HasPrefix(suffix, ".") && strings.HasSuffix(hostname, suffix) && len(hostname) > len(suffix)
+	}
+	return pattern == hostname
+}
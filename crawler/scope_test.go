@@ -0,0 +1,28 @@
+package crawler
+
+import "testing"
+
+func TestMatchDomain(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		hostname string
+		want     bool
+	}{
+		{"example.com", "example.com", true},
+		{"example.com", "www.example.com", false},
+		{"Example.COM", "example.com", true},
+		{" example.com ", "EXAMPLE.com", true},
+		{"*.example.com", "www.example.com", true},
+		{"*.example.com", "a.b.example.com", true},
+		{"*.example.com", "example.com", false},
+		{"*.example.com", "badexample.com", false},
+		{"*.example.com", "example.com.evil.org", false},
+		{"*example.com", "badexample.com", false},
+		{"*", "example.com", false},
+	}
+	for _, tt := range tests {
+		if got := MatchDomain(tt.pattern, tt.hostname); got != tt.want {
+			t.Errorf("MatchDomain(%q, %q) = %v, want %v", tt.pattern, tt.hostname, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,486 @@
+package crawler
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// SeenSet records the URLs the crawler has already queued or rejected.
+// Implementations must be safe for concurrent use.
+type SeenSet interface {
+	// Add records a URL and reports whether it was new
+	Add(url string) bool
+	// Contains reports whether a URL was added
+	Contains(url string) bool
+	// Len returns the number of URLs added
+	Len() int
+}
+
+// durableSeenSet is implemented by seen sets that keep their URLs on disk, such as DiskSeenSet.
+// Checkpoints do not copy them; each checkpoint starts a new generation of URLs instead, and a
+// resumed crawl rolls the set back to the generation its checkpoint started.
+type durableSeenSet interface {
+	SeenSet
+	Mark() (uint64, error)
+	Release(generation uint64) error
+	Rollback(generation uint64) error
+	Reset() error
+}
+
+// seenShards is the number of shards in a ShardedSeenSet
+const seenShards = 64
+
+// seenShard is one lock-protected part of a ShardedSeenSet
+type seenShard struct {
+	mu   sync.Mutex
+	urls map[string]struct{}
+}
+
+// ShardedSeenSet is an exact in-memory seen set split into shards to reduce lock contention
+type ShardedSeenSet struct {
+	shards [seenShards]seenShard
+}
+
+// NewShardedSeenSet creates an empty sharded seen set
+func NewShardedSeenSet() *ShardedSeenSet {
+	s := &ShardedSeenSet{}
+	for i := range s.shards {
+		s.shards[i].urls = make(map[string]struct{})
+	}
+	return s
+}
+
+// shard returns the shard holding a URL
+func (s *ShardedSeenSet) shard(url string) *seenShard {
+	h := fnv.New32a()
+	h.Write([]byte(url))
+	return &s.shards[h.Sum32()%seenShards]
+}
+
+// Add records a URL and reports whether it was new
+func (s *ShardedSeenSet) Add(url string) bool {
+	shard := s.shard(url)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	if _, ok := shard.urls[url]; ok {
+		return false
+	}
+	shard.urls[url] = struct{}{}
+	return true
+}
+
+// Contains reports whether a URL was added
+func (s *ShardedSeenSet) Contains(url string) bool {
+	shard := s.shard(url)
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+	_, ok := shard.urls[url]
+	return ok
+}
+
+// Len returns the number of URLs added
+func (s *ShardedSeenSet) Len() int {
+	n := 0
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+		n += len(s.shards[i].urls)
+		s.shards[i].mu.Unlock()
+	}
+	return n
+}
+
+// Each calls fn for every URL in the set
+func (s *ShardedSeenSet) Each(fn func(url string)) {
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+		for url := range s.shards[i].urls {
+			fn(url)
+		}
+		s.shards[i].mu.Unlock()
+	}
+}
+
+// bloomFilter is a fixed-size Bloom filter
+type bloomFilter struct {
+	bits     []uint64
+	m        uint64 // Number of bits
+	k        uint64 // Number of hash functions
+	capacity int    // URLs it holds before its false-positive rate is exceeded
+	count    int
+}
+
+// newBloomFilter sizes a Bloom filter for a capacity and false-positive rate
+func newBloomFilter(capacity int, fpRate float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(capacity) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Ceil(float64(m) / float64(capacity) * math.Ln2))
+	if k < 1 {
+		k = 1
+	}
+	return &bloomFilter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: capacity,
+	}
+}
+
+// bloomHashes returns the two base hashes combined into the k bit positions
+func bloomHashes(url string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	h1.Write([]byte(url))
+	h2 := fnv.New64()
+	h2.Write([]byte(url))
+	// An odd step visits distinct positions for every hash function
+	return h1.Sum64(), h2.Sum64() | 1
+}
+
+// has reports whether all bits for the hashes are set
+func (f *bloomFilter) has(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// add sets the bits for the hashes
+func (f *bloomFilter) add(h1, h2 uint64) {
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.count++
+}
+
+// BloomSeenSet is a scalable Bloom filter: a compact seen set that never forgets a URL but
+// may report a new URL as seen with a small probability, in which case that URL is not crawled.
+// When a filter fills up, a larger one with a tighter false-positive rate is added, so the
+// overall rate stays below the configured one however many URLs are added.
+type BloomSeenSet struct {
+	mu       sync.Mutex
+	filters  []*bloomFilter
+	fpRate   float64
+	capacity int // Capacity of the first filter
+	count    int
+}
+
+// NewBloomSeenSet creates a Bloom filter seen set sized for an initial number of URLs
+// and an overall false-positive rate, e.g. 0.001
+func NewBloomSeenSet(initialCapacity int, fpRate float64) *BloomSeenSet {
+	if initialCapacity <= 0 {
+		initialCapacity = 1000000
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		fpRate = 0.001
+	}
+	s := &BloomSeenSet{fpRate: fpRate, capacity: initialCapacity}
+	s.grow()
+	return s
+}
+
+// grow adds a filter with twice the capacity and half the false-positive rate of the last one.
+// The rates form a series that sums to fpRate. Callers must hold mu.
+func (s *BloomSeenSet) grow() {
+	n := len(s.filters)
+	capacity := s.capacity << n
+	fpRate := s.fpRate * math.Pow(0.5, float64(n+1))
+	s.filters = append(s.filters, newBloomFilter(capacity, fpRate))
+}
+
+// Add records a URL and reports whether it was new
+func (s *BloomSeenSet) Add(url string) bool {
+	h1, h2 := bloomHashes(url)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.contains(h1, h2) {
+		return false
+	}
+
+	current := s.filters[len(s.filters)-1]
+	current.add(h1, h2)
+	s.count++
+	if current.count >= current.capacity {
+		s.grow()
+	}
+	return true
+}
+
+// Contains reports whether a URL was probably added
+func (s *BloomSeenSet) Contains(url string) bool {
+	h1, h2 := bloomHashes(url)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.contains(h1, h2)
+}
+
+// contains checks every filter. Callers must hold mu.
+func (s *BloomSeenSet) contains(h1, h2 uint64) bool {
+	for _, f := range s.filters {
+		if f.has(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of URLs added
+func (s *BloomSeenSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Buckets and keys of a DiskSeenSet's database
+var (
+	seenBucket        = []byte("seen") // Every URL
+	seenMetaBucket    = []byte("meta")
+	seenGenerationKey = []byte("generation") // Current generation, in the meta bucket
+)
+
+// seenGenerationPrefix starts the names of the buckets listing the URLs added in each generation
+const seenGenerationPrefix = "generation-"
+
+// DiskSeenSet is an exact seen set stored in a bbolt database, for crawls whose URLs do not fit in memory.
+// The database is kept across runs: it is synced at every checkpoint, and a resumed crawl continues
+// with it after removing the URLs added since its checkpoint (see Mark and Rollback).
+type DiskSeenSet struct {
+	db    *bolt.DB
+	mu    sync.Mutex
+	count int
+}
+
+// NewDiskSeenSet opens the seen set in the database file at path, creating it if needed.
+// The URLs already in the file are kept; the crawler empties the set unless it resumes a crawl.
+func NewDiskSeenSet(path string) (*DiskSeenSet, error) {
+	// Checkpoints sync the database, so skip fsync on every write
+	db, err := bolt.Open(path, 0644, &bolt.Options{NoSync: true, NoFreelistSync: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open seen database: %w", err)
+	}
+	s := &DiskSeenSet{db: db}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(seenMetaBucket); err != nil {
+			return err
+		}
+		seen, err := tx.CreateBucketIfNotExists(seenBucket)
+		if err != nil {
+			return err
+		}
+		s.count = seen.Stats().KeyN
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create seen bucket: %w", err)
+	}
+	return s, nil
+}
+
+// Add records a URL and reports whether it was new
+func (s *DiskSeenSet) Add(url string) bool {
+	added := false
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(seenBucket)
+		if b.Get([]byte(url)) != nil {
+			return nil
+		}
+		added = true
+		if err := b.Put([]byte(url), []byte{}); err != nil {
+			return err
+		}
+		// Note the generation it was added in, so it can be rolled back. Generations start with
+		// the first Mark; without checkpoints nothing is ever rolled back.
+		current := currentGeneration(tx)
+		if current == 0 {
+			return nil
+		}
+		generation, err := tx.CreateBucketIfNotExists(seenGenerationBucket(current))
+		if err != nil {
+			return err
+		}
+		return generation.Put([]byte(url), []byte{})
+	})
+	if err != nil {
+		// Treat the URL as seen rather than risk crawling it twice
+		return false
+	}
+	if added {
+		s.mu.Lock()
+		s.count++
+		s.mu.Unlock()
+	}
+	return added
+}
+
+// Contains reports whether a URL was added
+func (s *DiskSeenSet) Contains(url string) bool {
+	found := false
+	s.db.View(func(tx *bolt.Tx) error {
+		found = tx.Bucket(seenBucket).Get([]byte(url)) != nil
+		return nil
+	})
+	return found
+}
+
+// Len returns the number of URLs added
+func (s *DiskSeenSet) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Each calls fn for every URL in the set
+func (s *DiskSeenSet) Each(fn func(url string)) {
+	s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(seenBucket).ForEach(func(k, _ []byte) error {
+			fn(string(k))
+			return nil
+		})
+	})
+}
+
+// Mark starts a new generation of URLs and syncs the database to disk. It returns the new
+// generation, which a checkpoint stores so the set can be rolled back to this point.
+// URLs added before the first Mark are not tracked by generation.
+func (s *DiskSeenSet) Mark() (uint64, error) {
+	var next uint64
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		next = currentGeneration(tx) + 1
+		value := make([]byte, 8)
+		binary.BigEndian.PutUint64(value, next)
+		return tx.Bucket(seenMetaBucket).Put(seenGenerationKey, value)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to start seen generation: %w", err)
+	}
+	if err := s.db.Sync(); err != nil {
+		return 0, fmt.Errorf("failed to sync seen database: %w", err)
+	}
+	return next, nil
+}
+
+// Release makes the URLs added before a generation permanent, once a checkpoint that stores the
+// generation is saved, so their generations need not be kept
+func (s *DiskSeenSet) Release(generation uint64) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, g := range generations(tx) {
+			if g >= generation {
+				continue
+			}
+			if err := tx.DeleteBucket(seenGenerationBucket(g)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release seen generations: %w", err)
+	}
+	return nil
+}
+
+// Rollback removes the URLs added since Mark returned the given generation
+func (s *DiskSeenSet) Rollback(generation uint64) error {
+	removed := 0
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		if current := currentGeneration(tx); generation > current {
+			return fmt.Errorf("the database is at generation %d, before the checkpoint's %d", current, generation)
+		}
+		seen := tx.Bucket(seenBucket)
+		for _, g := range generations(tx) {
+			if g < generation {
+				continue
+			}
+			name := seenGenerationBucket(g)
+			err := tx.Bucket(name).ForEach(func(k, _ []byte) error {
+				removed++
+				return seen.Delete(k)
+			})
+			if err != nil {
+				return err
+			}
+			if err := tx.DeleteBucket(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to roll back seen database: %w", err)
+	}
+
+	s.mu.Lock()
+	s.count -= removed
+	s.mu.Unlock()
+	return nil
+}
+
+// Reset removes every URL from the set and stops tracking generations until the next Mark
+func (s *DiskSeenSet) Reset() error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, g := range generations(tx) {
+			if err := tx.DeleteBucket(seenGenerationBucket(g)); err != nil {
+				return err
+			}
+		}
+		if err := tx.Bucket(seenMetaBucket).Delete(seenGenerationKey); err != nil {
+			return err
+		}
+		if err := tx.DeleteBucket(seenBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucket(seenBucket)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to empty seen database: %w", err)
+	}
+
+	s.mu.Lock()
+	s.count = 0
+	s.mu.Unlock()
+	return nil
+}
+
+// Close closes the database
+func (s *DiskSeenSet) Close() error {
+	return s.db.Close()
+}
+
+// currentGeneration returns the generation URLs are added in, or 0 before the first Mark
+func currentGeneration(tx *bolt.Tx) uint64 {
+	value := tx.Bucket(seenMetaBucket).Get(seenGenerationKey)
+	if len(value) != 8 {
+		return 0
+	}
+	return binary.BigEndian.Uint64(value)
+}
+
+// seenGenerationBucket returns the name of the bucket listing the URLs added in a generation
+func seenGenerationBucket(generation uint64) []byte {
+	return []byte(fmt.Sprintf("%s%016x", seenGenerationPrefix, generation))
+}
+
+// generations returns the generations that have URLs listed
+func generations(tx *bolt.Tx) []uint64 {
+	var found []uint64
+	tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+		if !strings.HasPrefix(string(name), seenGenerationPrefix) {
+			return nil
+		}
+		if g, err := strconv.ParseUint(string(name[len(seenGenerationPrefix):]), 16, 64); err == nil {
+			found = append(found, g)
+		}
+		return nil
+	})
+	return found
+}
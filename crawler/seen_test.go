@@ -0,0 +1,167 @@
+package crawler
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func TestBloomSeenSetFalsePositiveBound(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		fpRate   float64
+		added    int
+	}{
+		{"within capacity", 10000, 0.01, 10000},
+		{"tight rate", 10000, 0.001, 10000},
+		{"grown twice", 1000, 0.01, 7000},
+		{"grown many times", 1000, 0.01, 60000},
+	}
+	const probes = 100000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewBloomSeenSet(tt.capacity, tt.fpRate)
+			for i := 0; i < tt.added; i++ {
+				url := fmt.Sprintf("https://example.com/page/%d", i)
+				if !s.Add(url) {
+					// A false positive on Add is allowed, but must stay rare
+					continue
+				}
+				if s.Add(url) {
+					t.Fatalf("%s was added twice", url)
+				}
+			}
+			for i := 0; i < tt.added; i++ {
+				if url := fmt.Sprintf("https://example.com/page/%d", i); !s.Contains(url) {
+					t.Fatalf("%s was added but is not contained", url)
+				}
+			}
+
+			falsePositives := 0
+			for i := 0; i < probes; i++ {
+				if s.Contains(fmt.Sprintf("https://example.org/other/%d", i)) {
+					falsePositives++
+				}
+			}
+			if rate := float64(falsePositives) / probes; rate > tt.fpRate {
+				t.Errorf("false-positive rate %.5f exceeds %.5f (%d filters)", rate, tt.fpRate, len(s.filters))
+			}
+			if s.Len() > tt.added || s.Len() < tt.added-int(float64(tt.added)*tt.fpRate) {
+				t.Errorf("Len() = %d after adding %d URLs", s.Len(), tt.added)
+			}
+		})
+	}
+}
+
+func TestDiskSeenSetGenerations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "seen.db")
+	s, err := NewDiskSeenSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without checkpoints, URLs are only written once
+	s.Add("https://example.com/a")
+	if got := diskSeenGenerations(t, s); len(got) != 0 {
+		t.Errorf("generations before the first Mark = %v, want none", got)
+	}
+	checkpoint, err := s.Mark()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Release(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	s.Add("https://example.com/b")
+	if _, err := s.Mark(); err != nil { // A checkpoint that was never saved
+		t.Fatal(err)
+	}
+	s.Add("https://example.com/c")
+	if got := diskSeenGenerations(t, s); len(got) != 2 {
+		t.Errorf("generations = %v, want the two since the saved checkpoint", got)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The file is kept, and a resumed crawl rolls back what came after its checkpoint
+	s, err = NewDiskSeenSet(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if s.Len() != 3 {
+		t.Errorf("Len() after reopening = %d, want 3", s.Len())
+	}
+	if err := s.Rollback(checkpoint); err != nil {
+		t.Fatal(err)
+	}
+	for url, want := range map[string]bool{
+		"https://example.com/a": true,
+		"https://example.com/b": false,
+		"https://example.com/c": false,
+	} {
+		if got := s.Contains(url); got != want {
+			t.Errorf("Contains(%s) after rollback = %v, want %v", url, got, want)
+		}
+	}
+	if s.Len() != 1 {
+		t.Errorf("Len() after rollback = %d, want 1", s.Len())
+	}
+	if !s.Add("https://example.com/b") {
+		t.Error("rolled back URL could not be added again")
+	}
+
+	if err := s.Rollback(checkpoint + 10); err == nil {
+		t.Error("rollback to a generation the database never reached succeeded")
+	}
+
+	if err := s.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Len() != 0 || s.Contains("https://example.com/a") {
+		t.Errorf("set not empty after Reset: Len() = %d", s.Len())
+	}
+	s.Add("https://example.com/d")
+	if got := diskSeenGenerations(t, s); len(got) != 0 {
+		t.Errorf("generations after Reset = %v, want none until the next Mark", got)
+	}
+}
+
+func TestDiskSeenSetReleaseDropsOldGenerations(t *testing.T) {
+	s, err := NewDiskSeenSet(filepath.Join(t.TempDir(), "seen.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for i := 0; i < 5; i++ {
+		generation, err := s.Mark()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Release(generation); err != nil {
+			t.Fatal(err)
+		}
+		s.Add(fmt.Sprintf("https://example.com/%d", i))
+		if got := diskSeenGenerations(t, s); len(got) != 1 || got[0] != generation {
+			t.Errorf("generations after checkpoint %d = %v, want [%d]", i, got, generation)
+		}
+	}
+}
+
+// diskSeenGenerations returns the generations a DiskSeenSet lists URLs for
+func diskSeenGenerations(t *testing.T, s *DiskSeenSet) []uint64 {
+	t.Helper()
+	var found []uint64
+	if err := s.db.View(func(tx *bolt.Tx) error {
+		found = generations(tx)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return found
+}
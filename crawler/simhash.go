@@ -0,0 +1,57 @@
+package crawler
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// simHashShingle is the number of consecutive words hashed together as one feature
+const simHashShingle = 3
+
+// SimHash returns a 64-bit fingerprint of a text in which similar texts differ in few bits.
+// Features are overlapping three-word shingles of the lower-cased words, so reordered
+// boilerplate counts less than changed content.
+func SimHash(text string) uint64 {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+	if len(words) == 0 {
+		return 0
+	}
+
+	// Short texts are hashed word by word
+	size := simHashShingle
+	if len(words) < size {
+		size = 1
+	}
+
+	var weights [64]int
+	h := fnv.New64a()
+	for i := 0; i+size <= len(words); i++ {
+		h.Reset()
+		h.Write([]byte(strings.Join(words[i:i+size], " ")))
+		feature := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if feature&(1<<bit) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var fingerprint uint64
+	for bit := 0; bit < 64; bit++ {
+		if weights[bit] > 0 {
+			fingerprint |= 1 << bit
+		}
+	}
+	return fingerprint
+}
+
+// HammingDistance returns the number of bits in which two fingerprints differ
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
@@ -0,0 +1,76 @@
+package crawler
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHammingDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0xff, 0xff, 0},
+		{0, 1, 1},
+		{0, 1 << 63, 1},
+		{0x0f, 0xf0, 8},
+		{0, ^uint64(0), 64},
+	}
+	for _, tt := range tests {
+		if got := HammingDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("HammingDistance(%#x, %#x) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSimHash(t *testing.T) {
+	article := strings.Repeat("The quick brown fox jumps over the lazy dog while the farmer sleeps. ", 3) +
+		"Crawlers fetch pages, follow their links and record what they find along the way. " +
+		"Near duplicate detection groups pages whose text is almost the same."
+
+	tests := []struct {
+		name    string
+		a, b    string
+		maxDist int // Inclusive bound on the distance
+		minDist int
+	}{
+		{"identical", article, article, 0, 0},
+		{"case and punctuation are ignored", article, strings.ToUpper(strings.ReplaceAll(article, ".", "!")), 0, 0},
+		{"whitespace is ignored", "one two three four", "one\ttwo\n three  four", 0, 0},
+		{"one word changed", article, strings.Replace(article, "farmer", "farmers", 1), 12, 1},
+		{"sentence appended", article, article + " One more line at the end.", 12, 1},
+		{"unrelated text", article, "Completely different content about cooking pasta with tomatoes, garlic and basil in a pan.", 64, 16},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := HammingDistance(SimHash(tt.a), SimHash(tt.b))
+			if got < tt.minDist || got > tt.maxDist {
+				t.Errorf("distance = %d, want between %d and %d", got, tt.minDist, tt.maxDist)
+			}
+		})
+	}
+}
+
+func TestSimHashShortText(t *testing.T) {
+	tests := []struct {
+		text string
+		want uint64
+	}{
+		{"", 0},
+		{"  ...  ", 0},
+	}
+	for _, tt := range tests {
+		if got := SimHash(tt.text); got != tt.want {
+			t.Errorf("SimHash(%q) = %#x, want %#x", tt.text, got, tt.want)
+		}
+	}
+
+	// Texts shorter than a shingle are hashed word by word, so a single word still has a fingerprint
+	if SimHash("hello") == 0 || SimHash("hello") == SimHash("world") {
+		t.Errorf("single words hash to %#x and %#x", SimHash("hello"), SimHash("world"))
+	}
+	if SimHash("hello world") != SimHash("World, hello!") {
+		t.Errorf("word-by-word fingerprints depend on word order")
+	}
+}
@@ -0,0 +1,64 @@
+package crawler
+
+import "fmt"
+
+// openSink opens Config.Sink and writes the results restored from a checkpoint to it
+func (c *Crawler) openSink() error {
+	c.sinkMu.Lock()
+	defer c.sinkMu.Unlock()
+
+	if err := c.config.Sink.Open(); err != nil {
+		return fmt.Errorf("failed to open sink: %w", err)
+	}
+	c.sinkOpen = true
+
+	// Replay the results log, which holds the restored results before the workers start
+	if c.resultLog == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.resultLog.replay(func(result Result) error {
+		if err := c.config.Sink.Write(result); err != nil {
+			return fmt.Errorf("failed to write result: %w", err)
+		}
+		return nil
+	})
+}
+
+// writeResult streams a result to Config.Sink, if one is open
+func (c *Crawler) writeResult(result Result) {
+	if c.config.Sink == nil {
+		return
+	}
+	c.sinkMu.Lock()
+	defer c.sinkMu.Unlock()
+
+	// Workers still finishing after a cancellation may arrive once the sink is closed
+	if !c.sinkOpen {
+		return
+	}
+	if err := c.config.Sink.Write(result); err != nil {
+		c.config.Logger.Printf("Error writing result for %s: %v", result.URL, err)
+	}
+}
+
+// closeSink records the stop reason and crawl summary, when the sink can carry metadata, and closes Config.Sink
+func (c *Crawler) closeSink() error {
+	c.sinkMu.Lock()
+	defer c.sinkMu.Unlock()
+
+	if !c.sinkOpen {
+		return nil
+	}
+	c.sinkOpen = false
+
+	if m, ok := c.config.Sink.(metadataStorage); ok {
+		m.SetMetadata("stop_reason", c.StopReason())
+		m.SetMetadata("summary", c.Summary())
+	}
+	if err := c.config.Sink.Close(); err != nil {
+		return fmt.Errorf("failed to close sink: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package crawler
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxSitemapSize is the largest uncompressed sitemap the protocol allows (50 MiB)
+	maxSitemapSize = 50 * 1024 * 1024
+	// maxSitemapNesting limits how deep sitemap index files are followed
+	maxSitemapNesting = 3
+)
+
+// sitemapEntry is a <sitemap> or <url> element of a sitemap file
+type sitemapEntry struct {
+	Loc      string `xml:"loc"`
+	LastMod  string `xml:"lastmod"`
+	Priority string `xml:"priority"`
+}
+
+// sitemapDocument covers both sitemap index files and urlsets
+type sitemapDocument struct {
+	XMLName  xml.Name
+	Sitemaps []sitemapEntry `xml:"sitemap"`
+	URLs     []sitemapEntry `xml:"url"`
+}
+
+// discoverSitemaps finds the sitemaps of a seed URL's host and seeds the queue with their URLs.
+// It runs in its own goroutine and holds one pending job until it is done.
+func (c *Crawler) discoverSitemaps(seedURL string) {
+	defer c.decrementPendingJobs()
+
+	base, err := url.Parse(seedURL)
+	if err != nil {
+		return
+	}
+	root := base.Scheme + "://" + base.Host
+
+	// Sitemaps listed in robots.txt come first, /sitemap.xml is the conventional fallback
+	candidates := c.robots.get(c.ctx, base).sitemaps
+	candidates = append(candidates, root+"/sitemap.xml")
+
+	visited := make(map[string]bool)
+	seeded := 0
+	for _, sitemapURL := range candidates {
+		seeded += c.processSitemap(sitemapURL, base.Host, 0, visited)
+	}
+	c.config.Logger.Printf("Seeded %d URLs from sitemaps of %s", seeded, base.Host)
+}
+
+// processSitemap fetches one sitemap, follows index files and enqueues page URLs.
+// It returns the number of URLs added to the queue.
+func (c *Crawler) processSitemap(sitemapURL, host string, nesting int, visited map[string]bool) int {
+	if visited[sitemapURL] || nesting > maxSitemapNesting || c.ctx.Err() != nil {
+		return 0
+	}
+	visited[sitemapURL] = true
+
+	doc, err := c.fetchSitemap(sitemapURL)
+	if err != nil {
+		c.config.Logger.Printf("Error reading sitemap %s: %v", sitemapURL, err)
+		return 0
+	}
+
+	seeded := 0
+
+	// Sitemap index: follow every listed sitemap
+	for _, entry := range doc.Sitemaps {
+		if loc := strings.TrimSpace(entry.Loc); loc != "" {
+			seeded += c.processSitemap(loc, host, nesting+1, visited)
+		}
+	}
+
+	// Urlset: seed the queue with in-scope pages
+	for _, entry := range doc.URLs {
+		normalizedURL, err := NormalizeURL(strings.TrimSpace(entry.Loc))
+		if err != nil || !IsURLValid(normalizedURL) || !c.inScope(normalizedURL, host) {
+			continue
+		}
+		if c.hasURLBeenSeen(normalizedURL) || c.contentAction(normalizedURL) == ContentSkip || !c.passesFilter(normalizedURL) {
+			continue
+		}
+		if c.ctx.Err() != nil {
+			return seeded
+		}
+
+		priority, _ := strconv.ParseFloat(strings.TrimSpace(entry.Priority), 64)
+		seeded += c.enqueue(Job{URL: normalizedURL, Scope: host, LastMod: strings.TrimSpace(entry.LastMod), Priority: priority})
+	}
+
+	c.config.Logger.Printf("Sitemap %s: %d sitemaps, %d URLs", sitemapURL, len(doc.Sitemaps), len(doc.URLs))
+	return seeded
+}
+
+// fetchSitemap downloads and decodes a sitemap, transparently handling gzip
+func (c *Crawler) fetchSitemap(sitemapURL string) (*sitemapDocument, error) {
+	host := HostOf(sitemapURL)
+	if !c.hosts.acquire(c.ctx, host) {
+		return nil, c.ctx.Err()
+	}
+	defer c.hosts.release(host)
+
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, sitemapURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	return parseSitemap(resp.Body)
+}
+
+// parseSitemap decodes a sitemap index or urlset, which may be gzip-compressed
+func parseSitemap(r io.Reader) (*sitemapDocument, error) {
+	// Detect gzip by its magic bytes, since servers label .xml.gz files inconsistently
+	var body io.Reader = bufio.NewReader(r)
+	if magic, err := body.(*bufio.Reader).Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		body = gz
+	}
+
+	doc := &sitemapDocument{}
+	if err := xml.NewDecoder(io.LimitReader(body, maxSitemapSize)).Decode(doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
@@ -0,0 +1,107 @@
+package crawler
+
+import (
+	"bytes"
+	"compress/gzip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testURLSet = `<?xml version="1.0" encoding="UTF-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url>
+    <loc>https://example.com/</loc>
+    <lastmod>2024-01-02</lastmod>
+    <priority>1.0</priority>
+  </url>
+  <url>
+    <loc> https://example.com/about </loc>
+  </url>
+</urlset>`
+
+const testSitemapIndex = `<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap>
+    <loc>https://example.com/sitemap-pages.xml</loc>
+    <lastmod>2024-01-02T10:00:00+00:00</lastmod>
+  </sitemap>
+  <sitemap>
+    <loc>https://example.com/sitemap-posts.xml.gz</loc>
+  </sitemap>
+</sitemapindex>`
+
+// gzipped compresses a string
+func gzipped(t *testing.T, s string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestParseSitemap(t *testing.T) {
+	urls := []sitemapEntry{
+		{Loc: "https://example.com/", LastMod: "2024-01-02", Priority: "1.0"},
+		{Loc: " https://example.com/about "},
+	}
+	sitemaps := []sitemapEntry{
+		{Loc: "https://example.com/sitemap-pages.xml", LastMod: "2024-01-02T10:00:00+00:00"},
+		{Loc: "https://example.com/sitemap-posts.xml.gz"},
+	}
+
+	tests := []struct {
+		name         string
+		body         string
+		wantName     string
+		wantURLs     []sitemapEntry
+		wantSitemaps []sitemapEntry
+	}{
+		{"urlset", testURLSet, "urlset", urls, nil},
+		{"index", testSitemapIndex, "sitemapindex", nil, sitemaps},
+		{"gzip urlset", gzipped(t, testURLSet), "urlset", urls, nil},
+		{"gzip index", gzipped(t, testSitemapIndex), "sitemapindex", nil, sitemaps},
+		{"empty urlset", `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9"></urlset>`, "urlset", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc, err := parseSitemap(strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("parseSitemap: %v", err)
+			}
+			if doc.XMLName.Local != tt.wantName {
+				t.Errorf("root element = %q, want %q", doc.XMLName.Local, tt.wantName)
+			}
+			if !reflect.DeepEqual(doc.URLs, tt.wantURLs) {
+				t.Errorf("URLs = %+v, want %+v", doc.URLs, tt.wantURLs)
+			}
+			if !reflect.DeepEqual(doc.Sitemaps, tt.wantSitemaps) {
+				t.Errorf("sitemaps = %+v, want %+v", doc.Sitemaps, tt.wantSitemaps)
+			}
+		})
+	}
+}
+
+func TestParseSitemapErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"empty", ""},
+		{"not XML", "User-agent: *"},
+		{"truncated gzip", gzipped(t, testURLSet)[:20]},
+		{"bad gzip header", "\x1f\x8bnot gzip"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseSitemap(strings.NewReader(tt.body)); err == nil {
+				t.Error("parseSitemap succeeded, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"time"
+)
+
+// Summary describes a finished crawl, so its output can be understood without the command line that produced it
+type Summary struct {
+	StartedAt   time.Time      `json:"started_at"`
+	FinishedAt  time.Time      `json:"finished_at"`
+	Duration    string         `json:"duration"`
+	StopReason  string         `json:"stop_reason"`
+	Seeds       []string       `json:"seeds"`
+	Results     int            `json:"results"`      // Results recorded, including failed fetches
+	Fetched     int            `json:"fetched"`      // Pages counted against MaxPages
+	Bytes       int64          `json:"bytes"`        // Bytes downloaded
+	StatusCodes map[int]int    `json:"status_codes"` // Responses per HTTP status code
+	Errors      map[string]int `json:"errors"`       // Failed fetches per error category
+	Redirected  int            `json:"redirected"`   // Results reached through redirects
+	Duplicates  int            `json:"duplicates"`   // Results with the same content as an earlier one
+	Blocked     int            `json:"blocked_by_robots"`
+	Rejected    map[string]int `json:"rejected"` // URLs rejected per filter rule
+	Config      ConfigSummary  `json:"config"`
+}
+
+// ConfigSummary holds the settings of a crawl that affect its results
+type ConfigSummary struct {
+	MaxDepth         int      `json:"max_depth"`
+	Workers          int      `json:"workers"`
+	Timeout          string   `json:"timeout"`
+	RateLimit        string   `json:"rate_limit"`
+	MaxConnsPerHost  int      `json:"max_conns_per_host"`
+	MaxRedirects     int      `json:"max_redirects"`
+	AdaptiveThrottle bool     `json:"adaptive_throttle"`
+	MaxAttempts      int      `json:"max_attempts"`
+	UserAgent        string   `json:"user_agent"`
+	RespectRobots    bool     `json:"respect_robots"`
+	Sitemaps         bool     `json:"sitemaps"`
+	CheckLinks       bool     `json:"check_links,omitempty"`
+	AllowedDomains   []string `json:"allowed_domains,omitempty"`
+	Include          []string `json:"include,omitempty"`
+	Exclude          []string `json:"exclude,omitempty"`
+	Order            string   `json:"order"`
+	ContentPolicy    struct {
+		Fetch    []string `json:"fetch,omitempty"`
+		HeadOnly []string `json:"head_only,omitempty"`
+		Skip     []string `json:"skip,omitempty"`
+	} `json:"content_policy"`
+	MaxPages        int    `json:"max_pages,omitempty"`
+	MaxBytes        int64  `json:"max_bytes,omitempty"`
+	MaxPagesPerHost int    `json:"max_pages_per_host,omitempty"`
+	MaxBodySize     int64  `json:"max_body_size,omitempty"`
+	MaxDuration     string `json:"max_duration,omitempty"`
+	Resumed         bool   `json:"resumed,omitempty"`
+}
+
+// Summary returns a summary of the crawl so far; after Start returns it describes the whole crawl
+func (c *Crawler) Summary() Summary {
+	stats := c.Stats()
+	finished := time.Now()
+
+	c.mu.Lock()
+	started := c.startedAt
+	seeds := append([]string(nil), c.seeds...)
+	c.mu.Unlock()
+
+	return Summary{
+		StartedAt:   started,
+		FinishedAt:  finished,
+		Duration:    finished.Sub(started).Round(time.Millisecond).String(),
+		StopReason:  c.StopReason(),
+		Seeds:       seeds,
+		Results:     stats.Results,
+		Fetched:     stats.Pages,
+		Bytes:       stats.Bytes,
+		StatusCodes: stats.StatusCodes,
+		Errors:      stats.Errors,
+		Redirected:  stats.Redirected,
+		Duplicates:  stats.Duplicates,
+		Blocked:     stats.Blocked,
+		Rejected:    stats.Rejected,
+		Config:      c.configSummary(),
+	}
+}
+
+// configSummary describes the crawler's configuration
+func (c *Crawler) configSummary() ConfigSummary {
+	config := c.config
+	summary := ConfigSummary{
+		MaxDepth:         config.MaxDepth,
+		Workers:          config.NumWorkers,
+		Timeout:          config.Timeout.String(),
+		RateLimit:        config.RateLimit.String(),
+		MaxConnsPerHost:  config.MaxConnsPerHost,
+		MaxRedirects:     config.MaxRedirects,
+		AdaptiveThrottle: config.AdaptiveThrottle,
+		MaxAttempts:      config.Retry.MaxAttempts,
+		UserAgent:        config.UserAgent,
+		RespectRobots:    config.RespectRobots,
+		Sitemaps:         config.Sitemaps,
+		CheckLinks:       config.CheckLinks,
+		AllowedDomains:   config.AllowedDomains,
+		Order:            config.Ordering.Strategy,
+		MaxPages:         config.MaxPages,
+		MaxBytes:         config.MaxBytes,
+		MaxPagesPerHost:  config.MaxPagesPerHost,
+		MaxBodySize:      config.MaxBodySize,
+		Resumed:          config.Resume,
+	}
+	if summary.Order == "" {
+		summary.Order = OrderFIFO
+	}
+	if config.MaxDuration > 0 {
+		summary.MaxDuration = config.MaxDuration.String()
+	}
+	if config.Filter != nil {
+		for _, rule := range config.Filter.include {
+			summary.Include = append(summary.Include, rule.String())
+		}
+		for _, rule := range config.Filter.exclude {
+			summary.Exclude = append(summary.Exclude, rule.String())
+		}
+	}
+	summary.ContentPolicy.Fetch = config.ContentPolicy.Fetch
+	summary.ContentPolicy.HeadOnly = config.ContentPolicy.HeadOnly
+	summary.ContentPolicy.Skip = config.ContentPolicy.Skip
+	return summary
+}
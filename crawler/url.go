@@ -59,26 +59,18 @@ func IsURLValid(rawURL string) bool {
 		return false
 	}
 
-	// Parse the URL
+	// Parse the URL; which file types are crawled is decided by the ContentPolicy
+	_, err := url.Parse(rawURL)
+	return err == nil
+}
+
+// HostOf returns the host (with port) of a URL, or an empty string if it cannot be parsed
+func HostOf(rawURL string) string {
 	parsedURL, err := url.Parse(rawURL)
 	if err != nil {
-		return false
-	}
-
-	// Reject certain file types that are not webpages
-	excludedExtensions := []string{
-		".pdf", ".jpg", ".jpeg", ".png", ".gif", ".svg", ".css", ".js",
-		".zip", ".tar", ".gz", ".rar", ".exe", ".mp3", ".mp4", ".avi",
-		".mov", ".mkv", ".doc", ".docx", ".xls", ".xlsx", ".ppt", ".pptx",
+		return ""
 	}
-
-	for _, ext := range excludedExtensions {
-		if strings.HasSuffix(strings.ToLower(parsedURL.Path), ext) {
-			return false
-		}
-	}
-
-	return true
+	return parsedURL.Host
 }
 
 // ResolveURL resolves a relative URL against a base URL
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// stringList is a flag that can be given multiple times
+type stringList []string
+
+// String returns the flag values as a comma-separated list
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set appends a flag value
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(list string) []string {
+	var values []string
+	for _, field := range strings.Split(list, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			values = append(values, field)
+		}
+	}
+	return values
+}
+
+// readSeedsFile reads one URL per line, skipping blank lines and # comments
+func readSeedsFile(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var seeds []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		seeds = append(seeds, line)
+	}
+	return seeds, scanner.Err()
+}
+
+// parseStatusCodes parses a comma-separated list of HTTP status codes
+func parseStatusCodes(list string) ([]int, error) {
+	codes := []int{}
+	for _, field := range splitList(list) {
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status code %q", field)
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
@@ -6,6 +6,7 @@ import (
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -14,47 +15,316 @@ import (
 )
 
 func main() {
+	// "goCrawler check-links [flags]" crawls to find broken links instead of collecting pages
+	checkLinks := len(os.Args) > 1 && os.Args[1] == "check-links"
+	if checkLinks {
+		os.Args = append(os.Args[:1], os.Args[2:]...)
+	}
+
 	// Command line flags
-	startURL := flag.String("url", "", "Starting URL for crawling")
+	var startURLs stringList
+	flag.Var(&startURLs, "url", "Starting URL for crawling (repeat for multiple seeds)")
+	seedsFile := flag.String("seeds-file", "", "File with one seed URL per line")
+	var includes, excludes stringList
+	flag.Var(&includes, "include", "Only crawl URLs matching this rule: glob, regex:<expr> or prefix:<path> (repeatable)")
+	flag.Var(&excludes, "exclude", "Skip URLs matching this rule: glob, regex:<expr> or prefix:<path> (repeatable)")
+	pathPrefix := flag.String("path-prefix", "", "Only crawl URLs whose path starts with this prefix")
+	fetchTypes := flag.String("fetch-types", "", "Comma-separated MIME types to download as assets, e.g. application/pdf")
+	headTypes := flag.String("head-types", "", "Comma-separated MIME types to check with HEAD only, e.g. image/*")
+	skipTypes := flag.String("skip-types", "*", "Comma-separated MIME types not to request (HTML is always crawled); empty skips nothing")
+	allowedDomains := flag.String("allowed-domains", "", "Comma-separated extra domains to crawl, e.g. *.example.com")
 	maxDepth := flag.Int("depth", 2, "Maximum crawling depth")
 	numWorkers := flag.Int("workers", 5, "Number of concurrent workers")
-	outputFile := flag.String("output", "results.json", "Output file name (CSV or JSON)")
+	outputFile := flag.String("output", "results.json", "Output file name; the extension picks the format unless -format is set, and .gz compresses it")
+	warcFile := flag.String("warc", "", "Also archive every HTTP exchange to this WARC file (.warc.gz compresses each record)")
+	graphFile := flag.String("graph", "", "Also export the link graph to this file: .graphml, .dot, .gv or .csv for an edge list")
+	csvColumns := flag.String("csv-columns", "", "Comma-separated CSV columns to write: "+strings.Join(storage.CSVColumns(), ", "))
+	csvLinks := flag.String("csv-links", "", "With CSV output, also write one source-target row per link to this CSV file")
+	outputFormat := flag.String("format", "", "Output format: "+strings.Join(storage.Formats(), ", ")+" (default: from the -output extension)")
 	timeout := flag.Duration("timeout", 10*time.Second, "HTTP request timeout")
-	rateLimit := flag.Duration("rate", 100*time.Millisecond, "Rate limit between requests")
+	rateLimit := flag.Duration("rate", 100*time.Millisecond, "Minimum delay between requests to the same host")
+	hostConns := flag.Int("host-conns", 2, "Maximum concurrent connections per host")
+	adaptive := flag.Bool("adaptive", false, "Adapt per-host delays to latency and 429/503 responses")
+	maxHostDelay := flag.Duration("max-host-delay", 30*time.Second, "Upper bound for adaptive per-host delays")
+	retries := flag.Int("retries", 3, "Maximum attempts per URL for transient failures (1 disables retries)")
+	retryBase := flag.Duration("retry-base", 500*time.Millisecond, "Backoff before the first retry, doubled for each further attempt")
+	retryMax := flag.Duration("retry-max", 30*time.Second, "Maximum backoff between retries")
+	retryJitter := flag.Float64("retry-jitter", 0.5, "Fraction of each backoff that is randomised (0-1)")
+	retryStatus := flag.String("retry-status", "408,429,500,502,503,504", "Comma-separated HTTP status codes to retry")
+	maxPages := flag.Int("max-pages", 0, "Stop after fetching this many pages (0 = unlimited)")
+	maxBytes := flag.Int64("max-bytes", 0, "Stop after downloading this many bytes (0 = unlimited)")
+	maxPagesPerHost := flag.Int("max-pages-per-host", 0, "Fetch at most this many pages per host (0 = unlimited)")
+	maxBodySize := flag.Int64("max-body-size", 0, "Read at most this many bytes per response (0 = unlimited)")
+	maxDuration := flag.Duration("max-duration", 0, "Stop cleanly after this long, keeping all results (0 = unlimited)")
+	stateDir := flag.String("state-dir", "", "Directory for crawl checkpoints (enables checkpointing)")
+	checkpointInterval := flag.Duration("checkpoint-interval", time.Minute, "How often to save a checkpoint")
+	resume := flag.Bool("resume", false, "Resume the crawl from the checkpoint in -state-dir")
+	frontierDir := flag.String("frontier-dir", "", "Directory to spill the URL queue to when it outgrows memory")
+	frontierMemory := flag.Int("frontier-memory", 10000, "Queued URLs kept in memory before spilling to -frontier-dir")
+	order := flag.String("order", crawler.OrderFIFO, "Crawl order: fifo, bfs, dfs, best-first or round-robin")
+	var boosts stringList
+	flag.Var(&boosts, "boost", "Raise the best-first score of URLs matching a rule, e.g. glob:**/docs/**=2 (repeatable)")
+	seenSet := flag.String("seen-set", "memory", "Seen URL store: memory, bloom or disk")
+	bloomCapacity := flag.Int("bloom-capacity", 1000000, "URLs the first Bloom filter holds before another is added")
+	bloomFPRate := flag.Float64("bloom-fp-rate", 0.001, "Probability that the Bloom filter skips a new URL as seen")
+	seenDB := flag.String("seen-db", "seen.db", "Database file for -seen-set disk")
 	crawlTimeout := flag.Duration("crawl-timeout", 5*time.Minute, "Maximum time for crawling to run")
+	userAgent := flag.String("user-agent", crawler.DefaultUserAgent, "User-Agent header sent with requests")
+	respectRobots := flag.Bool("respect-robots", false, "Obey robots.txt rules and Crawl-delay")
+	sitemaps := flag.Bool("sitemaps", false, "Seed the crawl with URLs from robots.txt sitemaps and /sitemap.xml")
+	maxRedirects := flag.Int("max-redirects", 10, "Maximum redirects followed per request")
+	longRedirects := flag.Int("long-redirects", 3, "Flag redirect chains with more than this many hops (0 = never)")
+	redirectReportFile := flag.String("redirect-report", "", "Also write redirect loops and long chains to this JSON file and list them")
+	duplicatesReportFile := flag.String("duplicates-report", "", "Also write canonical, exact and near-duplicate page clusters to this JSON file")
+	simHashDistance := flag.Int("simhash-distance", 3, "Maximum differing SimHash bits for pages to count as near-duplicates")
+	reportFile := flag.String("report", "", "With check-links, also write the broken link report to this JSON file")
 	flag.Parse()
 
-	if *startURL == "" {
-		fmt.Println("Please provide a starting URL with -url flag")
+	// Link checks only write the results when -output is given
+	writeOutput := !checkLinks
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "output" {
+			writeOutput = true
+		}
+	})
+
+	// Exit with the status set at the end once the deferred cleanup has run
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
+	retryStatusCodes, err := parseStatusCodes(*retryStatus)
+	if err != nil {
+		fmt.Printf("Invalid -retry-status: %v\n", err)
+		os.Exit(1)
+	}
+
+	if *seedsFile != "" {
+		seeds, err := readSeedsFile(*seedsFile)
+		if err != nil {
+			fmt.Printf("Failed to read seeds file: %v\n", err)
+			os.Exit(1)
+		}
+		startURLs = append(startURLs, seeds...)
+	}
+
+	if *resume && *stateDir == "" {
+		fmt.Println("-resume requires -state-dir")
+		os.Exit(1)
+	}
+
+	if len(startURLs) == 0 {
+		fmt.Println("Please provide a starting URL with -url flag or -seeds-file")
 		flag.Usage()
 		os.Exit(1)
 	}
 
+	if *pathPrefix != "" {
+		includes = append(includes, crawler.FilterPrefix+":"+*pathPrefix)
+	}
+	filter, err := crawler.NewURLFilter(includes, excludes)
+	if err != nil {
+		fmt.Printf("Invalid URL filter: %v\n", err)
+		os.Exit(1)
+	}
+
+	strategy, err := crawler.ParseOrderStrategy(*order)
+	if err != nil {
+		fmt.Printf("Invalid crawl order: %v\n", err)
+		os.Exit(1)
+	}
+	var scoreBoosts []crawler.Boost
+	for _, spec := range boosts {
+		boost, err := crawler.ParseBoost(spec)
+		if err != nil {
+			fmt.Printf("Invalid boost: %v\n", err)
+			os.Exit(1)
+		}
+		scoreBoosts = append(scoreBoosts, boost)
+	}
+	ordering := crawler.Ordering{
+		Strategy: strategy,
+		Score:    crawler.BoostedScore(crawler.DefaultScore, scoreBoosts),
+	}
+
+	// Keep the queue in memory unless a spill directory is given
+	var frontier crawler.Frontier
+	if *frontierDir != "" {
+		frontier, err = crawler.NewDiskFrontier(*frontierDir, *frontierMemory, ordering)
+		if err != nil {
+			fmt.Printf("Failed to set up frontier: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Pick the seen URL store
+	var seen crawler.SeenSet
+	switch *seenSet {
+	case "memory":
+	case "bloom":
+		seen = crawler.NewBloomSeenSet(*bloomCapacity, *bloomFPRate)
+	case "disk":
+		diskSeen, err := crawler.NewDiskSeenSet(*seenDB)
+		if err != nil {
+			fmt.Printf("Failed to set up seen set: %v\n", err)
+			os.Exit(1)
+		}
+		defer diskSeen.Close()
+		seen = diskSeen
+	default:
+		fmt.Printf("Unknown seen set %q, expected memory, bloom or disk\n", *seenSet)
+		exitCode = 1
+		return
+	}
+
 	// Setup logger
 	logFile, err := os.OpenFile("crawler.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
 	if err != nil {
-		log.Fatalf("Failed to open log file: %v", err)
+		fmt.Printf("Failed to open log file: %v\n", err)
+		exitCode = 1
+		return
 	}
 	defer logFile.Close()
 	logger := log.New(logFile, "", log.LstdFlags)
 
-	// Setup storage based on file extension
-	var store storage.Storage
-	if storage.IsJSONFile(*outputFile) {
-		store = storage.NewJSONStorage(*outputFile)
-	} else {
-		store = storage.NewCSVStorage(*outputFile)
+	// Setup storage based on -format or the file extension
+	store, err := storage.New(*outputFormat, *outputFile)
+	if err != nil {
+		fmt.Printf("Invalid output: %v\n", err)
+		exitCode = 1
+		return
+	}
+
+	// Apply the CSV options
+	if *csvColumns != "" || *csvLinks != "" {
+		csvStore, ok := store.(*storage.CSVStorage)
+		if !ok {
+			fmt.Println("-csv-columns and -csv-links require CSV output")
+			exitCode = 1
+			return
+		}
+		if *csvColumns != "" {
+			if err := csvStore.SetColumns(splitList(*csvColumns)); err != nil {
+				fmt.Printf("Invalid CSV columns: %v\n", err)
+				exitCode = 1
+				return
+			}
+		}
+		if *csvLinks != "" {
+			csvStore.SetLinksFile(*csvLinks)
+		}
+	}
+
+	var sinks []storage.Sink
+	if writeOutput {
+		sinks = append(sinks, store)
+	}
+
+	// Export the link graph alongside the results
+	if *graphFile != "" {
+		graph, err := storage.NewGraphStorage(*graphFile)
+		if err != nil {
+			fmt.Printf("Invalid graph output: %v\n", err)
+			exitCode = 1
+			return
+		}
+		sinks = append(sinks, graph)
+	}
+
+	// Collect the broken links when checking links
+	var report *storage.LinkReport
+	if checkLinks {
+		report = storage.NewLinkReport(*reportFile)
+		sinks = append(sinks, report)
+	}
+
+	// Flag redirect loops and long chains when asked to
+	var redirects *storage.RedirectReport
+	if *redirectReportFile != "" {
+		redirects = storage.NewRedirectReport(*redirectReportFile, *longRedirects)
+		sinks = append(sinks, redirects)
+	}
+
+	// Group duplicate pages to count the unique ones when asked to
+	var duplicates *storage.DuplicateReport
+	if *duplicatesReportFile != "" {
+		duplicates = storage.NewDuplicateReport(*duplicatesReportFile, *simHashDistance)
+		sinks = append(sinks, duplicates)
+	}
+
+	var sink crawler.ResultSink
+	if len(sinks) == 1 {
+		sink = sinks[0]
+	} else if len(sinks) > 1 {
+		sink = storage.NewMultiSink(sinks...)
+	}
+
+	// Archive raw exchanges when asked to
+	var archiver crawler.Archiver
+	if *warcFile != "" {
+		warc := storage.NewWARCWriter(*warcFile)
+		warc.SetUserAgent(*userAgent)
+		if err := warc.Open(); err != nil {
+			fmt.Printf("Failed to set up WARC archive: %v\n", err)
+			exitCode = 1
+			return
+		}
+		defer warc.Close()
+		archiver = warc
+	}
+
+	// An explicitly empty -skip-types skips nothing rather than falling back to the default policy
+	skip := splitList(*skipTypes)
+	if skip == nil {
+		skip = []string{}
 	}
 
 	// Create and configure crawler
 	c := crawler.New(crawler.Config{
-		StartURL:   *startURL,
-		MaxDepth:   *maxDepth,
-		NumWorkers: *numWorkers,
-		Timeout:    *timeout,
-		RateLimit:  *rateLimit,
-		Logger:     logger,
-		Storage:    store,
+		StartURLs:          startURLs,
+		AllowedDomains:     splitList(*allowedDomains),
+		Filter:             filter,
+		MaxPages:           *maxPages,
+		MaxBytes:           *maxBytes,
+		MaxPagesPerHost:    *maxPagesPerHost,
+		MaxBodySize:        *maxBodySize,
+		MaxDuration:        *maxDuration,
+		StateDir:           *stateDir,
+		CheckpointInterval: *checkpointInterval,
+		Resume:             *resume,
+		Frontier:           frontier,
+		Ordering:           ordering,
+		SeenSet:            seen,
+		ContentPolicy: crawler.ContentPolicy{
+			Fetch:    splitList(*fetchTypes),
+			HeadOnly: splitList(*headTypes),
+			Skip:     skip,
+		},
+		MaxDepth:         *maxDepth,
+		NumWorkers:       *numWorkers,
+		Timeout:          *timeout,
+		RateLimit:        *rateLimit,
+		MaxConnsPerHost:  *hostConns,
+		MaxRedirects:     *maxRedirects,
+		AdaptiveThrottle: *adaptive,
+		MaxHostDelay:     *maxHostDelay,
+		Retry: crawler.RetryPolicy{
+			MaxAttempts:          *retries,
+			BaseBackoff:          *retryBase,
+			MaxBackoff:           *retryMax,
+			Jitter:               *retryJitter,
+			RetryableStatusCodes: retryStatusCodes,
+		},
+		UserAgent:     *userAgent,
+		RespectRobots: *respectRobots,
+		Sitemaps:      *sitemaps,
+		CheckLinks:    checkLinks,
+		Logger:        logger,
+		Sink:          sink,
+		Archiver:      archiver,
 	})
 
 	// Setup graceful shutdown
@@ -87,7 +357,7 @@ func main() {
 
 	// Start crawling
 	fmt.Printf("Starting crawler at %s with %d workers and max depth %d\n",
-		*startURL, *numWorkers, *maxDepth)
+		strings.Join(startURLs, ", "), *numWorkers, *maxDepth)
 
 	// Start a goroutine to show progress
 	if *maxDepth > 0 {
@@ -113,15 +383,77 @@ func main() {
 	}
 
 	start := time.Now()
-	results, err := c.Start()
+	_, err = c.Start()
 	elapsed := time.Since(start)
 
 	if err != nil {
 		fmt.Printf("\nCrawler error: %v\n", err)
-		os.Exit(1)
+		exitCode = 1
+		return
+	}
+
+	fmt.Printf("\nCrawling completed in %s (%s)\n", elapsed, c.StopReason())
+	stats := c.Stats()
+	fmt.Printf("Found %d unique URLs\n", stats.Results)
+	if *respectRobots {
+		fmt.Printf("Skipped %d URLs disallowed by robots.txt\n", stats.Blocked)
+	}
+	for category, count := range stats.Errors {
+		fmt.Printf("Failed %d URLs with %s errors\n", count, category)
+	}
+	for rule, count := range stats.Rejected {
+		fmt.Printf("Rejected %d URLs by %s\n", count, rule)
+	}
+	if *adaptive {
+		for host, hostStats := range stats.Hosts {
+			fmt.Printf("Host %s: delay %s, latency %s, %d requests\n", host, hostStats.Delay, hostStats.Latency, hostStats.Requests)
+		}
+	}
+	if writeOutput {
+		fmt.Printf("Results saved to %s\n", *outputFile)
+	}
+	if *csvLinks != "" {
+		fmt.Printf("Links saved to %s\n", *csvLinks)
+	}
+	if *graphFile != "" {
+		fmt.Printf("Link graph saved to %s\n", *graphFile)
 	}
 
-	fmt.Printf("\nCrawling completed in %s\n", elapsed)
-	fmt.Printf("Found %d unique URLs\n", len(results))
-	fmt.Printf("Results saved to %s\n", *outputFile)
+	// Report duplicate pages
+	if duplicates != nil {
+		if pages := duplicates.Pages(); pages > 0 {
+			clusters, unique := duplicates.Clusters()
+			kinds := make(map[string]int)
+			for _, cluster := range clusters {
+				kinds[cluster.Kind]++
+			}
+			fmt.Printf("Unique pages: %d of %d (%d canonical groups, %d exact and %d near-duplicate clusters)\n",
+				unique, pages, kinds[storage.ClusterCanonical], kinds[storage.ClusterExact], kinds[storage.ClusterNear])
+		}
+		fmt.Printf("Duplicate report saved to %s\n", *duplicatesReportFile)
+	}
+
+	// Report redirect problems
+	if redirects != nil {
+		fmt.Printf("Redirect report saved to %s\n", *redirectReportFile)
+		if flagged := redirects.Flagged(); len(flagged) > 0 {
+			fmt.Printf("\nFound %d redirect loops or long chains:\n", len(flagged))
+			redirects.WriteText(os.Stdout)
+		}
+	}
+
+	// Report the broken links and fail, so a link check can gate a deployment
+	if report != nil {
+		broken := report.Broken()
+		if *reportFile != "" {
+			fmt.Printf("Link report saved to %s\n", *reportFile)
+		}
+		if len(broken) == 0 {
+			fmt.Println("No broken links found")
+			return
+		}
+		fmt.Printf("\nFound %d broken links:\n", len(broken))
+		report.WriteText(os.Stdout)
+		exitCode = 1
+	}
 }
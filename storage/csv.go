@@ -3,144 +3,222 @@ package storage
 import (
 	"encoding/csv"
 	"fmt"
-	"os"
-	"reflect"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/Taiizor/goCrawler/crawler"
 )
 
-// CSVStorage implements Storage interface for CSV format
+// csvColumn is a named CSV column and how its value is taken from a result
+type csvColumn struct {
+	name  string
+	value func(result crawler.Result) string
+}
+
+// csvListSeparator joins list values, such as headings, that may contain spaces
+const csvListSeparator = " | "
+
+// csvColumns lists every available column
+var csvColumns = []csvColumn{
+	{"url", func(r crawler.Result) string { return r.URL }},
+	{"title", func(r crawler.Result) string { return r.Title }},
+	{"status_code", func(r crawler.Result) string { return strconv.Itoa(r.StatusCode) }},
+	{"depth", func(r crawler.Result) string { return strconv.Itoa(r.Depth) }},
+	{"content_type", func(r crawler.Result) string { return r.ContentType }},
+	{"content_length", func(r crawler.Result) string { return strconv.FormatInt(r.ContentLength, 10) }},
+	{"links_count", func(r crawler.Result) string { return strconv.Itoa(len(r.Links)) }},
+	{"attempts", func(r crawler.Result) string { return strconv.Itoa(r.Attempts) }},
+	{"timestamp", func(r crawler.Result) string { return r.Timestamp.Format(time.RFC3339) }},
+	{"asset", func(r crawler.Result) string { return strconv.FormatBool(r.Asset) }},
+	{"truncated", func(r crawler.Result) string { return strconv.FormatBool(r.Truncated) }},
+	{"blocked_by_robots", func(r crawler.Result) string { return strconv.FormatBool(r.BlockedByRobots) }},
+	{"sitemap_lastmod", func(r crawler.Result) string { return r.SitemapLastMod }},
+	{"sitemap_priority", func(r crawler.Result) string {
+		if r.SitemapPriority == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(r.SitemapPriority, 'f', -1, 64)
+	}},
+	{"final_url", func(r crawler.Result) string { return r.FinalURL }},
+	{"redirect_count", func(r crawler.Result) string { return strconv.Itoa(len(r.Redirects)) }},
+	{"check_only", func(r crawler.Result) string { return strconv.FormatBool(r.CheckOnly) }},
+	{"canonical", func(r crawler.Result) string { return metadata(r).Canonical }},
+	{"content_hash", func(r crawler.Result) string { return r.ContentHash }},
+	{"simhash", func(r crawler.Result) string { return r.SimHash }},
+	{"duplicate_of", func(r crawler.Result) string { return r.DuplicateOf }},
+	{"error", func(r crawler.Result) string { return r.Error }},
+	{"error_category", func(r crawler.Result) string { return r.ErrorCategory }},
+	{"meta_description", func(r crawler.Result) string { return metadata(r).Description }},
+	{"meta_robots", func(r crawler.Result) string { return metadata(r).Robots }},
+	{"lang", func(r crawler.Result) string { return metadata(r).Lang }},
+	{"h1", func(r crawler.Result) string { return strings.Join(metadata(r).H1, csvListSeparator) }},
+	{"h1_count", func(r crawler.Result) string { return strconv.Itoa(len(metadata(r).H1)) }},
+	{"h2", func(r crawler.Result) string { return strings.Join(metadata(r).H2, csvListSeparator) }},
+	{"h3", func(r crawler.Result) string { return strings.Join(metadata(r).H3, csvListSeparator) }},
+	{"og_title", func(r crawler.Result) string { return metadata(r).OpenGraph["og:title"] }},
+	{"og_description", func(r crawler.Result) string { return metadata(r).OpenGraph["og:description"] }},
+	{"og_image", func(r crawler.Result) string { return metadata(r).OpenGraph["og:image"] }},
+	{"og_type", func(r crawler.Result) string { return metadata(r).OpenGraph["og:type"] }},
+	{"og_url", func(r crawler.Result) string { return metadata(r).OpenGraph["og:url"] }},
+	{"twitter_card", func(r crawler.Result) string { return metadata(r).Twitter["twitter:card"] }},
+	{"twitter_title", func(r crawler.Result) string { return metadata(r).Twitter["twitter:title"] }},
+	{"twitter_description", func(r crawler.Result) string { return metadata(r).Twitter["twitter:description"] }},
+	{"twitter_image", func(r crawler.Result) string { return metadata(r).Twitter["twitter:image"] }},
+	{"hreflang", func(r crawler.Result) string {
+		hreflang := metadata(r).Hreflang
+		alternates := make([]string, len(hreflang))
+		for i, alternate := range hreflang {
+			alternates[i] = alternate.Lang + "=" + alternate.URL
+		}
+		return strings.Join(alternates, " ")
+	}},
+	{"links", func(r crawler.Result) string { return strings.Join(r.Links, " ") }},
+}
+
+// DefaultCSVColumns are the columns written unless others are selected; the full link list
+// is only written on request
+var DefaultCSVColumns = []string{
+	"url", "title", "status_code", "depth", "content_type", "content_length", "links_count",
+	"attempts", "timestamp", "asset", "truncated", "blocked_by_robots", "sitemap_lastmod", "sitemap_priority",
+	"error", "error_category",
+}
+
+// CSVColumns returns the names of the available CSV columns
+func CSVColumns() []string {
+	names := make([]string, len(csvColumns))
+	for i, column := range csvColumns {
+		names[i] = column.name
+	}
+	return names
+}
+
+// CSVStorage implements Storage interface for CSV format with one row per page.
+// The columns are fixed unless chosen with SetColumns, so the header is the same for every crawl.
+// A .gz file name enables gzip compression. Crawl metadata is written to a separate file (see MetadataPath).
 type CSVStorage struct {
+	sidecar
 	filePath string
+	columns  []csvColumn
+	out      *output
+	writer   *csv.Writer
+	links    *EdgeListStorage // Optional second file with one row per link
 }
 
 // NewCSVStorage creates a new CSVStorage instance
 func NewCSVStorage(filePath string) *CSVStorage {
+	columns, _ := lookupCSVColumns(DefaultCSVColumns)
 	return &CSVStorage{
 		filePath: filePath,
+		columns:  columns,
 	}
 }
 
-// Save writes the crawl results to a CSV file
-func (s *CSVStorage) Save(results interface{}) error {
+// SetColumns selects the columns to write, in order, by name
+func (s *CSVStorage) SetColumns(names []string) error {
+	columns, err := lookupCSVColumns(names)
+	if err != nil {
+		return err
+	}
+	if len(columns) == 0 {
+		return fmt.Errorf("no CSV columns selected")
+	}
+	s.columns = columns
+	return nil
+}
+
+// lookupCSVColumns returns the columns with the given names, in order
+func lookupCSVColumns(names []string) ([]csvColumn, error) {
+	columns := make([]csvColumn, 0, len(names))
+	for _, name := range names {
+		found := false
+		for _, column := range csvColumns {
+			if column.name == strings.ToLower(strings.TrimSpace(name)) {
+				columns = append(columns, column)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown CSV column %q (available: %s)", name, strings.Join(CSVColumns(), ", "))
+		}
+	}
+	return columns, nil
+}
+
+// SetLinksFile also writes every link to a second CSV file, one source-target row per link target
+func (s *CSVStorage) SetLinksFile(filePath string) {
+	s.links = NewEdgeListStorage(filePath)
+}
+
+// Open creates the CSV file, and the links file if set, and writes the header
+func (s *CSVStorage) Open() error {
 	// Create the file
-	file, err := os.Create(s.filePath)
+	out, err := createOutput(s.filePath)
 	if err != nil {
 		return fmt.Errorf("failed to create CSV file: %w", err)
 	}
-	defer file.Close()
+	s.out = out
 
 	// Create CSV writer
-	writer := csv.NewWriter(file)
-	defer writer.Flush()
+	s.writer = csv.NewWriter(out)
 
-	// Try to convert results to a slice
-	val := reflect.ValueOf(results)
-	if val.Kind() != reflect.Slice {
-		return fmt.Errorf("expected slice but got %T", results)
+	headers := make([]string, len(s.columns))
+	for i, column := range s.columns {
+		headers[i] = column.name
+	}
+	if err := s.writer.Write(headers); err != nil {
+		s.out.Close()
+		return fmt.Errorf("failed to write CSV header: %w", err)
 	}
 
-	if val.Len() == 0 {
-		// Write header for empty results
-		err = writer.Write([]string{"URL", "Title", "StatusCode", "ContentLength", "Depth", "Timestamp", "LinksCount"})
-		if err != nil {
-			return fmt.Errorf("failed to write CSV header: %w", err)
-		}
-		return nil
-	}
-
-	// Extract first item to determine structure
-	firstItem := val.Index(0).Interface()
-	firstItemValue := reflect.ValueOf(firstItem)
-	firstItemType := firstItemValue.Type()
-
-	// If first item is a struct, write headers based on struct fields
-	if firstItemType.Kind() == reflect.Struct {
-		var headers []string
-		for i := 0; i < firstItemType.NumField(); i++ {
-			field := firstItemType.Field(i)
-			// Skip unexported fields
-			if field.PkgPath != "" {
-				continue
-			}
-
-			// Use JSON tag if available, otherwise use field name
-			tag := field.Tag.Get("json")
-			if tag == "" {
-				headers = append(headers, field.Name)
-			} else {
-				// Split to handle tag options like omitempty
-				headers = append(headers, tag)
-			}
+	if s.links != nil {
+		if err := s.links.Open(); err != nil {
+			s.out.Close()
+			return err
 		}
+	}
+	return nil
+}
 
-		// Add special header for links count
-		headers = append(headers, "LinksCount")
-
-		// Write headers
-		if err := writer.Write(headers); err != nil {
-			return fmt.Errorf("failed to write CSV header: %w", err)
-		}
+// Write adds a row for a result, and its links to the links file
+func (s *CSVStorage) Write(result crawler.Result) error {
+	row := make([]string, len(s.columns))
+	for i, column := range s.columns {
+		row[i] = column.value(result)
+	}
+	if err := s.writer.Write(row); err != nil {
+		return fmt.Errorf("failed to write CSV row: %w", err)
 	}
 
-	// Write data rows
-	for i := 0; i < val.Len(); i++ {
-		item := val.Index(i).Interface()
-		itemValue := reflect.ValueOf(item)
-
-		var row []string
-		if itemValue.Kind() == reflect.Struct {
-			for j := 0; j < itemValue.NumField(); j++ {
-				field := itemValue.Field(j)
-
-				// Skip unexported fields
-				if itemValue.Type().Field(j).PkgPath != "" {
-					continue
-				}
-
-				// Handle different field types
-				var fieldStr string
-				switch field.Kind() {
-				case reflect.String:
-					fieldStr = field.String()
-				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-					fieldStr = strconv.FormatInt(field.Int(), 10)
-				case reflect.Float32, reflect.Float64:
-					fieldStr = strconv.FormatFloat(field.Float(), 'f', 2, 64)
-				case reflect.Bool:
-					fieldStr = strconv.FormatBool(field.Bool())
-				case reflect.Struct:
-					// Handle Time type specifically
-					if field.Type() == reflect.TypeOf(time.Time{}) {
-						t := field.Interface().(time.Time)
-						fieldStr = t.Format(time.RFC3339)
-					} else {
-						fieldStr = fmt.Sprintf("%v", field.Interface())
-					}
-				case reflect.Slice:
-					// For links field, just store the count
-					if itemValue.Type().Field(j).Name == "Links" {
-						row = append(row, strconv.Itoa(field.Len()))
-						continue
-					} else {
-						fieldStr = fmt.Sprintf("%v", field.Interface())
-					}
-				default:
-					fieldStr = fmt.Sprintf("%v", field.Interface())
-				}
-
-				row = append(row, fieldStr)
-			}
+	if s.links != nil {
+		return s.links.Write(result)
+	}
+	return nil
+}
 
-		} else {
-			// Handle non-struct items (unlikely in our case)
-			row = append(row, fmt.Sprintf("%v", item))
-		}
+// Close flushes the rows, closes the files and writes the metadata file
+func (s *CSVStorage) Close() error {
+	var linksErr error
+	if s.links != nil {
+		linksErr = s.links.Close()
+	}
 
-		if err := writer.Write(row); err != nil {
-			return fmt.Errorf("failed to write CSV row: %w", err)
-		}
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		s.out.Close()
+		return fmt.Errorf("failed to write CSV: %w", err)
 	}
+	if err := s.out.Close(); err != nil {
+		return err
+	}
+	if linksErr != nil {
+		return linksErr
+	}
+	return s.writeSidecar(s.filePath)
+}
 
-	return nil
+// Save writes the crawl results to a CSV file
+func (s *CSVStorage) Save(results interface{}) error {
+	return saveAll(s, results)
 }
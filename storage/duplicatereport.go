@@ -0,0 +1,285 @@
+package storage
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+// Kinds of duplicate clusters found by DuplicateReport
+const (
+	ClusterCanonical = "canonical" // Pages that declare, or are, the same canonical URL
+	ClusterExact     = "exact"     // Pages with identical HTML
+	ClusterNear      = "near"      // Pages whose text fingerprints differ in few bits
+)
+
+// DuplicateCluster is a group of URLs serving the same page
+type DuplicateCluster struct {
+	Kind string   `json:"kind"`
+	Key  string   `json:"key"` // Canonical URL, content hash or, for near-duplicates, the first URL
+	URLs []string `json:"urls"`
+}
+
+// duplicatePage holds what DuplicateReport needs to know about a crawled page
+type duplicatePage struct {
+	url       string
+	canonical string
+	hash      string
+	simHash   uint64
+	hasSim    bool
+}
+
+// DuplicateReport implements Sink by grouping the crawled pages by canonical URL, content hash
+// and SimHash, so the number of unique pages can be told apart from the number of URLs
+type DuplicateReport struct {
+	filePath    string
+	maxDistance int
+	pages       []duplicatePage
+}
+
+// NewDuplicateReport creates a duplicate report that treats pages whose SimHash fingerprints differ
+// in at most maxDistance bits as near-duplicates. It is written as JSON to filePath on Close
+// unless filePath is empty.
+func NewDuplicateReport(filePath string, maxDistance int) *DuplicateReport {
+	if maxDistance < 0 {
+		maxDistance = 0
+	}
+	return &DuplicateReport{
+		filePath:    filePath,
+		maxDistance: maxDistance,
+	}
+}
+
+// Open starts an empty report
+func (r *DuplicateReport) Open() error {
+	r.pages = nil
+	return nil
+}
+
+// Write records a parsed HTML page; other results have no content to compare
+func (r *DuplicateReport) Write(result crawler.Result) error {
+	if result.ContentHash == "" {
+		return nil
+	}
+
+	page := duplicatePage{
+		url:       result.URL,
+		canonical: metadata(result).Canonical,
+		hash:      result.ContentHash,
+	}
+	if page.canonical == "" {
+		page.canonical = result.URL
+		if result.FinalURL != "" {
+			page.canonical = result.FinalURL
+		}
+	}
+	if simHash, err := strconv.ParseUint(result.SimHash, 16, 64); err == nil {
+		page.simHash = simHash
+		page.hasSim = true
+	}
+	r.pages = append(r.pages, page)
+	return nil
+}
+
+// Close writes the report file, if one was requested
+func (r *DuplicateReport) Close() error {
+	if r.filePath == "" {
+		return nil
+	}
+
+	clusters, unique := r.Clusters()
+	return writeJSONReport(r.filePath, map[string]interface{}{
+		"pages":        len(r.pages),
+		"unique_pages": unique,
+		"max_distance": r.maxDistance,
+		"clusters":     clusters,
+	})
+}
+
+// Pages returns the number of HTML pages seen
+func (r *DuplicateReport) Pages() int {
+	return len(r.pages)
+}
+
+// Clusters returns the duplicate clusters sorted by kind and key, and the number of unique pages
+// once all canonical, exact and near-duplicates are counted as one
+func (r *DuplicateReport) Clusters() ([]DuplicateCluster, int) {
+	all := newUnionFind(len(r.pages))
+	var clusters []DuplicateCluster
+
+	// Pages sharing a canonical URL or content hash
+	for _, group := range []struct {
+		kind string
+		key  func(p duplicatePage) string
+	}{
+		{ClusterCanonical, func(p duplicatePage) string { return p.canonical }},
+		{ClusterExact, func(p duplicatePage) string { return p.hash }},
+	} {
+		byKey := make(map[string][]int)
+		for i, page := range r.pages {
+			byKey[group.key(page)] = append(byKey[group.key(page)], i)
+		}
+		for key, members := range byKey {
+			if len(members) < 2 {
+				continue
+			}
+			for _, member := range members[1:] {
+				all.union(members[0], member)
+			}
+			clusters = append(clusters, DuplicateCluster{Kind: group.kind, Key: key, URLs: r.urls(members)})
+		}
+	}
+
+	// Near-duplicates are clustered separately, then merged into the overall count
+	near := newUnionFind(len(r.pages))
+	for _, pair := range r.nearPairs() {
+		near.union(pair[0], pair[1])
+		all.union(pair[0], pair[1])
+	}
+	for _, members := range near.groups() {
+		if len(members) < 2 {
+			continue
+		}
+		// Clusters of identical pages are already reported as exact duplicates
+		identical := true
+		for _, member := range members[1:] {
+			if r.pages[member].hash != r.pages[members[0]].hash {
+				identical = false
+				break
+			}
+		}
+		if identical {
+			continue
+		}
+		urls := r.urls(members)
+		clusters = append(clusters, DuplicateCluster{Kind: ClusterNear, Key: urls[0], URLs: urls})
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		if clusters[i].Kind != clusters[j].Kind {
+			return clusters[i].Kind < clusters[j].Kind
+		}
+		return clusters[i].Key < clusters[j].Key
+	})
+	return clusters, len(all.groups())
+}
+
+// nearPairs returns the pairs of pages whose fingerprints are within maxDistance bits.
+// Fingerprints are split into maxDistance+1 bands: two fingerprints that close must agree on at
+// least one band, so only pages sharing a band are compared.
+func (r *DuplicateReport) nearPairs() [][2]int {
+	// Compare each distinct fingerprint once
+	bySim := make(map[uint64][]int)
+	var fingerprints []uint64
+	for i, page := range r.pages {
+		if !page.hasSim {
+			continue
+		}
+		if _, ok := bySim[page.simHash]; !ok {
+			fingerprints = append(fingerprints, page.simHash)
+		}
+		bySim[page.simHash] = append(bySim[page.simHash], i)
+	}
+
+	var pairs [][2]int
+	for _, members := range bySim {
+		for _, member := range members[1:] {
+			pairs = append(pairs, [2]int{members[0], member})
+		}
+	}
+	if r.maxDistance == 0 {
+		return pairs
+	}
+
+	bands := r.maxDistance + 1
+	if bands > 64 {
+		bands = 64
+	}
+	width := 64 / bands
+	compared := make(map[[2]uint64]bool)
+	for band := 0; band < bands; band++ {
+		shift := uint(band * width)
+		bandWidth := width
+		if band == bands-1 {
+			bandWidth = 64 - band*width
+		}
+		mask := uint64(1)<<uint(bandWidth) - 1
+		if bandWidth == 64 {
+			mask = ^uint64(0)
+		}
+
+		buckets := make(map[uint64][]uint64)
+		for _, fingerprint := range fingerprints {
+			value := (fingerprint >> shift) & mask
+			buckets[value] = append(buckets[value], fingerprint)
+		}
+		for _, bucket := range buckets {
+			for i := 0; i < len(bucket); i++ {
+				for j := i + 1; j < len(bucket); j++ {
+					key := [2]uint64{bucket[i], bucket[j]}
+					if compared[key] {
+						continue
+					}
+					compared[key] = true
+					if crawler.HammingDistance(bucket[i], bucket[j]) <= r.maxDistance {
+						pairs = append(pairs, [2]int{bySim[bucket[i]][0], bySim[bucket[j]][0]})
+					}
+				}
+			}
+		}
+	}
+	return pairs
+}
+
+// urls returns the sorted URLs of the given pages
+func (r *DuplicateReport) urls(members []int) []string {
+	urls := make([]string, len(members))
+	for i, member := range members {
+		urls[i] = r.pages[member].url
+	}
+	sort.Strings(urls)
+	return urls
+}
+
+// unionFind groups items connected by union calls
+type unionFind struct {
+	parent []int
+}
+
+// newUnionFind creates n items, each in a group of its own
+func newUnionFind(n int) *unionFind {
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = i
+	}
+	return &unionFind{parent: parent}
+}
+
+// find returns the representative of an item's group
+func (u *unionFind) find(i int) int {
+	for u.parent[i] != i {
+		u.parent[i] = u.parent[u.parent[i]]
+		i = u.parent[i]
+	}
+	return i
+}
+
+// union merges the groups of two items
+func (u *unionFind) union(a, b int) {
+	u.parent[u.find(a)] = u.find(b)
+}
+
+// groups returns the members of every group
+func (u *unionFind) groups() [][]int {
+	byRoot := make(map[int][]int)
+	for i := range u.parent {
+		root := u.find(i)
+		byRoot[root] = append(byRoot[root], i)
+	}
+	groups := make([][]int, 0, len(byRoot))
+	for _, members := range byRoot {
+		groups = append(groups, members)
+	}
+	return groups
+}
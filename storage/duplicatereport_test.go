@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+// duplicateResult returns a parsed page result as DuplicateReport sees it
+func duplicateResult(url, canonical, hash string, simHash uint64) crawler.Result {
+	result := crawler.Result{
+		URL:         url,
+		ContentHash: hash,
+		SimHash:     strconv.FormatUint(simHash, 16),
+	}
+	if canonical != "" {
+		result.Metadata = &crawler.Metadata{Canonical: canonical}
+	}
+	return result
+}
+
+func TestDuplicateReportNearPairs(t *testing.T) {
+	// Random fingerprints, each with a few variants a handful of bits away
+	rng := rand.New(rand.NewSource(1))
+	var fingerprints []uint64
+	for i := 0; i < 60; i++ {
+		base := rng.Uint64()
+		fingerprints = append(fingerprints, base)
+		for flips := 1; flips <= 10; flips += 3 {
+			variant := base
+			for f := 0; f < flips; f++ {
+				variant ^= 1 << uint(rng.Intn(64))
+			}
+			fingerprints = append(fingerprints, variant)
+		}
+	}
+	// Repeated fingerprints pair up at any distance
+	fingerprints = append(fingerprints, fingerprints[0], fingerprints[5], fingerprints[5])
+
+	tests := []struct {
+		name        string
+		maxDistance int
+	}{
+		{"exact only", 0},
+		{"one bit", 1},
+		{"three bits", 3},
+		{"eight bits", 8},
+		{"more bands than fit evenly", 20},
+		{"one bit per band", 63},
+		{"capped at 64 bands", 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewDuplicateReport("", tt.maxDistance)
+			r.Open()
+			for i, fingerprint := range fingerprints {
+				r.Write(duplicateResult("http://example.com/"+strconv.Itoa(i), "", strconv.Itoa(i), fingerprint))
+			}
+
+			got := make(map[[2]int]bool)
+			for _, pair := range r.nearPairs() {
+				if pair[0] > pair[1] {
+					pair[0], pair[1] = pair[1], pair[0]
+				}
+				if got[pair] {
+					t.Errorf("pair %v reported twice", pair)
+				}
+				got[pair] = true
+			}
+
+			// Compare against every pair of distinct fingerprints, represented by their first page
+			first := make(map[uint64]int)
+			want := make(map[[2]int]bool)
+			for i, fingerprint := range fingerprints {
+				if j, ok := first[fingerprint]; ok {
+					want[[2]int{j, i}] = true
+					continue
+				}
+				first[fingerprint] = i
+			}
+			for a, i := range first {
+				for b, j := range first {
+					if i < j && crawler.HammingDistance(a, b) <= tt.maxDistance {
+						want[[2]int{i, j}] = true
+					}
+				}
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("got %d pairs, want %d", len(got), len(want))
+				for pair := range want {
+					if !got[pair] {
+						t.Errorf("missing pair %v at distance %d", pair,
+							crawler.HammingDistance(fingerprints[pair[0]], fingerprints[pair[1]]))
+					}
+				}
+				for pair := range got {
+					if !want[pair] {
+						t.Errorf("unexpected pair %v", pair)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestDuplicateReportClusters(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxDistance int
+		results     []crawler.Result
+		want        []DuplicateCluster
+		wantUnique  int
+	}{
+		{
+			name:        "all distinct",
+			maxDistance: 3,
+			results: []crawler.Result{
+				duplicateResult("http://a/1", "", "h1", 0x0000),
+				duplicateResult("http://a/2", "", "h2", 0xffff),
+			},
+			wantUnique: 2,
+		},
+		{
+			name:        "shared canonical",
+			maxDistance: 0,
+			results: []crawler.Result{
+				duplicateResult("http://a/page", "", "h1", 0x0000),
+				duplicateResult("http://a/page?ref=x", "http://a/page", "h2", 0xffff),
+				duplicateResult("http://a/other", "", "h3", 0xff00),
+			},
+			want: []DuplicateCluster{
+				{Kind: ClusterCanonical, Key: "http://a/page", URLs: []string{"http://a/page", "http://a/page?ref=x"}},
+			},
+			wantUnique: 2,
+		},
+		{
+			name:        "identical content is not also reported as near",
+			maxDistance: 3,
+			results: []crawler.Result{
+				duplicateResult("http://a/1", "", "same", 0x1234),
+				duplicateResult("http://a/2", "", "same", 0x1234),
+			},
+			want: []DuplicateCluster{
+				{Kind: ClusterExact, Key: "same", URLs: []string{"http://a/1", "http://a/2"}},
+			},
+			wantUnique: 1,
+		},
+		{
+			name:        "near duplicates chain",
+			maxDistance: 2,
+			results: []crawler.Result{
+				duplicateResult("http://a/1", "", "h1", 0x00),
+				duplicateResult("http://a/2", "", "h2", 0x03),
+				duplicateResult("http://a/3", "", "h3", 0x0f),
+				duplicateResult("http://a/4", "", "h4", 0xff00ff00),
+			},
+			want: []DuplicateCluster{
+				{Kind: ClusterNear, Key: "http://a/1", URLs: []string{"http://a/1", "http://a/2", "http://a/3"}},
+			},
+			wantUnique: 2,
+		},
+		{
+			name:        "beyond the distance",
+			maxDistance: 1,
+			results: []crawler.Result{
+				duplicateResult("http://a/1", "", "h1", 0x00),
+				duplicateResult("http://a/2", "", "h2", 0x03),
+			},
+			wantUnique: 2,
+		},
+		{
+			name:        "kinds merge into one unique page",
+			maxDistance: 1,
+			results: []crawler.Result{
+				duplicateResult("http://a/1", "", "h1", 0x00),
+				duplicateResult("http://a/2", "http://a/1", "h2", 0xff),
+				duplicateResult("http://a/3", "", "h2", 0xff),
+				duplicateResult("http://a/4", "", "h4", 0xfe),
+			},
+			want: []DuplicateCluster{
+				{Kind: ClusterCanonical, Key: "http://a/1", URLs: []string{"http://a/1", "http://a/2"}},
+				{Kind: ClusterExact, Key: "h2", URLs: []string{"http://a/2", "http://a/3"}},
+				{Kind: ClusterNear, Key: "http://a/2", URLs: []string{"http://a/2", "http://a/3", "http://a/4"}},
+			},
+			wantUnique: 1,
+		},
+		{
+			name:        "results without content are skipped",
+			maxDistance: 0,
+			results: []crawler.Result{
+				{URL: "http://a/image.png"},
+				{URL: "http://a/image.png?v=2"},
+				duplicateResult("http://a/1", "", "h1", 0x00),
+			},
+			wantUnique: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewDuplicateReport("", tt.maxDistance)
+			r.Open()
+			for _, result := range tt.results {
+				r.Write(result)
+			}
+
+			got, unique := r.Clusters()
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("clusters = %+v, want %+v", got, tt.want)
+			}
+			if unique != tt.wantUnique {
+				t.Errorf("unique pages = %d, want %d", unique, tt.wantUnique)
+			}
+		})
+	}
+}
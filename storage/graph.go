@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+// graphEdge is a link from a page to a target, merged when the page links to it more than once
+type graphEdge struct {
+	target string
+	text   string // Anchor text of the first link
+	weight int    // Number of links from the page to the target
+}
+
+// pageEdges returns the de-duplicated outgoing edges of a page in document order
+func pageEdges(result crawler.Result) []graphEdge {
+	var edges []graphEdge
+	index := make(map[string]int)
+	for _, link := range outlinks(result) {
+		if i, ok := index[link.URL]; ok {
+			edges[i].weight++
+			continue
+		}
+		index[link.URL] = len(edges)
+		edges = append(edges, graphEdge{target: link.URL, text: link.Text, weight: 1})
+	}
+	return edges
+}
+
+// NewGraphStorage creates a link graph exporter chosen by the file extension:
+// .graphml for GraphML, .dot or .gv for Graphviz and .csv for an edge list.
+// A trailing .gz enables gzip compression.
+func NewGraphStorage(filePath string) (Storage, error) {
+	switch FormatOf(filePath) {
+	case "graphml":
+		return NewGraphMLStorage(filePath), nil
+	case "dot", "gv":
+		return NewDOTStorage(filePath), nil
+	case "csv":
+		return NewEdgeListStorage(filePath), nil
+	}
+	return nil, fmt.Errorf("unknown graph format for %s (use .graphml, .dot, .gv or .csv)", filepath.Base(filePath))
+}
+
+// GraphMLStorage implements Storage interface for GraphML, which Gephi, yEd and networkx can load.
+// Crawled pages become nodes with their depth, status, title and content type; links become
+// weighted edges. Link targets that were not crawled are added as nodes when the storage is closed.
+type GraphMLStorage struct {
+	sidecar
+	filePath string
+	out      *output
+	nodes    map[string]bool // Crawled pages written so far
+	missing  map[string]int  // Link targets not crawled yet, with the number of the edge that first linked to them
+	edges    int
+}
+
+// NewGraphMLStorage creates a new GraphMLStorage instance
+func NewGraphMLStorage(filePath string) *GraphMLStorage {
+	return &GraphMLStorage{
+		filePath: filePath,
+	}
+}
+
+// Open creates the GraphML file and declares the node and edge attributes
+func (s *GraphMLStorage) Open() error {
+	out, err := createOutput(s.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create GraphML file: %w", err)
+	}
+	s.out = out
+	s.nodes = make(map[string]bool)
+	s.missing = make(map[string]int)
+	s.edges = 0
+
+	_, err = s.out.WriteString(xml.Header + `<graphml xmlns="http://graphml.graphdrawing.org/xmlns">
+  <key id="label" for="node" attr.name="label" attr.type="string"/>
+  <key id="url" for="node" attr.name="url" attr.type="string"/>
+  <key id="title" for="node" attr.name="title" attr.type="string"/>
+  <key id="depth" for="node" attr.name="depth" attr.type="int"/>
+  <key id="status" for="node" attr.name="status" attr.type="int"/>
+  <key id="content_type" for="node" attr.name="content_type" attr.type="string"/>
+  <key id="crawled" for="node" attr.name="crawled" attr.type="boolean"><default>false</default></key>
+  <key id="weight" for="edge" attr.name="weight" attr.type="double"/>
+  <key id="anchor" for="edge" attr.name="anchor" attr.type="string"/>
+  <graph id="crawl" edgedefault="directed">
+`)
+	if err != nil {
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+	return nil
+}
+
+// Write adds the page as a node and its links as edges
+func (s *GraphMLStorage) Write(result crawler.Result) error {
+	label := result.Title
+	if label == "" {
+		label = result.URL
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "    <node id=\"%s\">\n", xmlEscape(result.URL))
+	fmt.Fprintf(&b, "      <data key=\"label\">%s</data>\n", xmlEscape(label))
+	fmt.Fprintf(&b, "      <data key=\"url\">%s</data>\n", xmlEscape(result.URL))
+	fmt.Fprintf(&b, "      <data key=\"title\">%s</data>\n", xmlEscape(result.Title))
+	fmt.Fprintf(&b, "      <data key=\"depth\">%d</data>\n", result.Depth)
+	fmt.Fprintf(&b, "      <data key=\"status\">%d</data>\n", result.StatusCode)
+	fmt.Fprintf(&b, "      <data key=\"content_type\">%s</data>\n", xmlEscape(result.ContentType))
+	b.WriteString("      <data key=\"crawled\">true</data>\n")
+	b.WriteString("    </node>\n")
+	s.nodes[result.URL] = true
+	delete(s.missing, result.URL)
+
+	for _, edge := range pageEdges(result) {
+		s.edges++
+		fmt.Fprintf(&b, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", s.edges, xmlEscape(result.URL), xmlEscape(edge.target))
+		fmt.Fprintf(&b, "      <data key=\"weight\">%d</data>\n", edge.weight)
+		if edge.text != "" {
+			fmt.Fprintf(&b, "      <data key=\"anchor\">%s</data>\n", xmlEscape(edge.text))
+		}
+		b.WriteString("    </edge>\n")
+		if _, ok := s.missing[edge.target]; !ok && !s.nodes[edge.target] {
+			s.missing[edge.target] = s.edges
+		}
+	}
+
+	if _, err := s.out.WriteString(b.String()); err != nil {
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+	return nil
+}
+
+// Close adds nodes for link targets that were never crawled, closes the file and writes the metadata file
+func (s *GraphMLStorage) Close() error {
+	targets := make([]string, 0, len(s.missing))
+	for target := range s.missing {
+		targets = append(targets, target)
+	}
+	sort.Slice(targets, func(i, j int) bool { return s.missing[targets[i]] < s.missing[targets[j]] })
+
+	var b strings.Builder
+	for _, target := range targets {
+		fmt.Fprintf(&b, "    <node id=\"%s\">\n", xmlEscape(target))
+		fmt.Fprintf(&b, "      <data key=\"label\">%s</data>\n", xmlEscape(target))
+		fmt.Fprintf(&b, "      <data key=\"url\">%s</data>\n", xmlEscape(target))
+		b.WriteString("    </node>\n")
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+
+	if _, err := s.out.WriteString(b.String()); err != nil {
+		s.out.Close()
+		return fmt.Errorf("failed to write GraphML: %w", err)
+	}
+	if err := s.out.Close(); err != nil {
+		return err
+	}
+	return s.writeSidecar(s.filePath)
+}
+
+// Save writes the link graph of the crawl results to a GraphML file
+func (s *GraphMLStorage) Save(results interface{}) error {
+	return saveAll(s, results)
+}
+
+// xmlEscape escapes text for use in XML content and attributes
+func xmlEscape(text string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// DOTStorage implements Storage interface for Graphviz DOT.
+// Crawled pages become nodes labelled with their title; uncrawled link targets are drawn dashed.
+type DOTStorage struct {
+	sidecar
+	filePath string
+	out      *output
+}
+
+// NewDOTStorage creates a new DOTStorage instance
+func NewDOTStorage(filePath string) *DOTStorage {
+	return &DOTStorage{
+		filePath: filePath,
+	}
+}
+
+// Open creates the DOT file and starts the graph
+func (s *DOTStorage) Open() error {
+	out, err := createOutput(s.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create DOT file: %w", err)
+	}
+	s.out = out
+
+	if _, err := s.out.WriteString("digraph crawl {\n  node [shape=box, style=dashed];\n"); err != nil {
+		return fmt.Errorf("failed to write DOT: %w", err)
+	}
+	return nil
+}
+
+// Write adds the page as a node and its links as edges
+func (s *DOTStorage) Write(result crawler.Result) error {
+	label := result.Title
+	if label == "" {
+		label = result.URL
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "  %s [label=%s, style=solid, URL=%s, depth=%d, status=%d];\n",
+		dotQuote(result.URL), dotQuote(label), dotQuote(result.URL), result.Depth, result.StatusCode)
+	for _, edge := range pageEdges(result) {
+		fmt.Fprintf(&b, "  %s -> %s [weight=%d", dotQuote(result.URL), dotQuote(edge.target), edge.weight)
+		if edge.text != "" {
+			fmt.Fprintf(&b, ", tooltip=%s", dotQuote(edge.text))
+		}
+		b.WriteString("];\n")
+	}
+
+	if _, err := s.out.WriteString(b.String()); err != nil {
+		return fmt.Errorf("failed to write DOT: %w", err)
+	}
+	return nil
+}
+
+// Close ends the graph, closes the file and writes the metadata file
+func (s *DOTStorage) Close() error {
+	if _, err := s.out.WriteString("}\n"); err != nil {
+		s.out.Close()
+		return fmt.Errorf("failed to write DOT: %w", err)
+	}
+	if err := s.out.Close(); err != nil {
+		return err
+	}
+	return s.writeSidecar(s.filePath)
+}
+
+// Save writes the link graph of the crawl results to a DOT file
+func (s *DOTStorage) Save(results interface{}) error {
+	return saveAll(s, results)
+}
+
+// dotQuote quotes a string as a DOT ID
+func dotQuote(text string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(text) + `"`
+}
+
+// EdgeListStorage implements Storage interface for a CSV edge list with one row per link:
+// source, target, weight and anchor text, plus the source page's depth and status
+type EdgeListStorage struct {
+	sidecar
+	filePath string
+	out      *output
+	writer   *csv.Writer
+}
+
+// NewEdgeListStorage creates a new EdgeListStorage instance
+func NewEdgeListStorage(filePath string) *EdgeListStorage {
+	return &EdgeListStorage{
+		filePath: filePath,
+	}
+}
+
+// Open creates the edge list file and writes the header
+func (s *EdgeListStorage) Open() error {
+	out, err := createOutput(s.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create edge list file: %w", err)
+	}
+	s.out = out
+	s.writer = csv.NewWriter(out)
+
+	if err := s.writer.Write([]string{"source", "target", "weight", "anchor_text", "source_depth", "source_status"}); err != nil {
+		return fmt.Errorf("failed to write CSV header: %w", err)
+	}
+	return nil
+}
+
+// Write adds a row for every distinct link on the page
+func (s *EdgeListStorage) Write(result crawler.Result) error {
+	depth := strconv.Itoa(result.Depth)
+	status := strconv.Itoa(result.StatusCode)
+	for _, edge := range pageEdges(result) {
+		row := []string{result.URL, edge.target, strconv.Itoa(edge.weight), edge.text, depth, status}
+		if err := s.writer.Write(row); err != nil {
+			return fmt.Errorf("failed to write CSV row: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close flushes the rows, closes the file and writes the metadata file
+func (s *EdgeListStorage) Close() error {
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		s.out.Close()
+		return fmt.Errorf("failed to write CSV: %w", err)
+	}
+	if err := s.out.Close(); err != nil {
+		return err
+	}
+	return s.writeSidecar(s.filePath)
+}
+
+// Save writes the link graph of the crawl results to a CSV edge list
+func (s *EdgeListStorage) Save(results interface{}) error {
+	return saveAll(s, results)
+}
@@ -0,0 +1,50 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+func TestGraphMLStorageMissingNodes(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "graph.graphml")
+	s := NewGraphMLStorage(filePath)
+	if err := s.Open(); err != nil {
+		t.Fatal(err)
+	}
+
+	// b is linked before and after it is crawled; c and d are never crawled
+	results := []crawler.Result{
+		{URL: "http://a/", Links: []string{"http://a/b", "http://a/c", "http://a/b"}},
+		{URL: "http://a/b", Links: []string{"http://a/", "http://a/d", "http://a/c"}},
+		{URL: "http://a/e", Links: []string{"http://a/b", "http://a/d"}},
+	}
+	for _, result := range results {
+		if err := s.Write(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.missing) != 2 {
+		t.Errorf("missing targets = %v, want c and d only", s.missing)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	graph := string(data)
+	for _, url := range []string{"http://a/", "http://a/b", "http://a/c", "http://a/d", "http://a/e"} {
+		if got := strings.Count(graph, `<node id="`+url+`">`); got != 1 {
+			t.Errorf("%s has %d nodes, want 1", url, got)
+		}
+	}
+	if c, d := strings.Index(graph, `<node id="http://a/c">`), strings.Index(graph, `<node id="http://a/d">`); c > d {
+		t.Errorf("uncrawled nodes are not in the order they were first linked")
+	}
+}
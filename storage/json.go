@@ -3,62 +3,114 @@ package storage
 import (
 	"encoding/json"
 	"fmt"
-	"os"
+	"sort"
 	"time"
+
+	"github.com/Taiizor/goCrawler/crawler"
 )
 
-// JSONStorage implements Storage interface for JSON format
+// JSONStorage implements Storage interface for JSON format.
+// Results are streamed into the "results" array; the count, timestamp and metadata
+// are written after it when the storage is closed. A .gz file name enables gzip compression.
 type JSONStorage struct {
 	filePath string
+	metadata map[string]interface{}
+	writer   *output
+	count    int
 }
 
 // NewJSONStorage creates a new JSONStorage instance
 func NewJSONStorage(filePath string) *JSONStorage {
 	return &JSONStorage{
 		filePath: filePath,
+		metadata: make(map[string]interface{}),
 	}
 }
 
-// Save writes the crawl results to a JSON file
-func (s *JSONStorage) Save(results interface{}) error {
-	// Create the file
-	file, err := os.Create(s.filePath)
+// SetMetadata adds a top-level field, such as the crawl's stop reason, to the output
+func (s *JSONStorage) SetMetadata(key string, value interface{}) {
+	s.metadata[key] = value
+}
+
+// Open creates the JSON file and starts the results array
+func (s *JSONStorage) Open() error {
+	writer, err := createOutput(s.filePath)
 	if err != nil {
 		return fmt.Errorf("failed to create JSON file: %w", err)
 	}
-	defer file.Close()
+	s.writer = writer
+	s.count = 0
 
-	// Add metadata to the output
-	output := map[string]interface{}{
-		"results":   results,
-		"count":     getResultCount(results),
-		"timestamp": time.Now().Format(time.RFC3339),
+	if _, err := s.writer.WriteString("{\n  \"results\": ["); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
 	}
+	return nil
+}
 
-	// Create an encoder with pretty printing
-	encoder := json.NewEncoder(file)
-	encoder.SetIndent("", "  ")
-
-	// Encode the data
-	if err := encoder.Encode(output); err != nil {
+// Write appends a result to the results array
+func (s *JSONStorage) Write(result crawler.Result) error {
+	data, err := json.MarshalIndent(result, "    ", "  ")
+	if err != nil {
 		return fmt.Errorf("failed to encode JSON: %w", err)
 	}
 
+	separator := ",\n    "
+	if s.count == 0 {
+		separator = "\n    "
+	}
+	if _, err := s.writer.WriteString(separator); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	if _, err := s.writer.Write(data); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	s.count++
 	return nil
 }
 
-// getResultCount tries to determine the count of results
-func getResultCount(results interface{}) int {
-	// Try to get length of slice
-	if slice, ok := results.([]interface{}); ok {
-		return len(slice)
+// Close ends the results array, writes the count, timestamp and metadata and closes the file
+func (s *JSONStorage) Close() error {
+	if s.count > 0 {
+		if _, err := s.writer.WriteString("\n  "); err != nil {
+			return fmt.Errorf("failed to write JSON: %w", err)
+		}
+	}
+	if _, err := s.writer.WriteString("]"); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
 	}
 
-	// Try with specific type
-	if typedResults, ok := results.([]struct{}); ok {
-		return len(typedResults)
+	// Add metadata to the output
+	fields := map[string]interface{}{
+		"count":     s.count,
+		"timestamp": time.Now().Format(time.RFC3339),
 	}
+	for key, value := range s.metadata {
+		fields[key] = value
+	}
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
 
-	// If we can't determine the count, return 0
-	return 0
+	for _, key := range keys {
+		name, _ := json.Marshal(key)
+		value, err := json.MarshalIndent(fields[key], "  ", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to encode JSON: %w", err)
+		}
+		if _, err := fmt.Fprintf(s.writer, ",\n  %s: %s", name, value); err != nil {
+			return fmt.Errorf("failed to write JSON: %w", err)
+		}
+	}
+
+	if _, err := s.writer.WriteString("\n}\n"); err != nil {
+		return fmt.Errorf("failed to write JSON: %w", err)
+	}
+	return s.writer.Close()
+}
+
+// Save writes the crawl results to a JSON file
+func (s *JSONStorage) Save(results interface{}) error {
+	return saveAll(s, results)
 }
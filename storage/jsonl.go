@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+// JSONLStorage implements Storage interface for JSON Lines (NDJSON) format, one result per line.
+// Crawl metadata is written to a separate file (see MetadataPath).
+type JSONLStorage struct {
+	sidecar
+	filePath string
+	out      *output
+}
+
+// NewJSONLStorage creates a new JSONLStorage instance; a .gz file name enables gzip compression
+func NewJSONLStorage(filePath string) *JSONLStorage {
+	return &JSONLStorage{
+		filePath: filePath,
+	}
+}
+
+// Open creates the JSON Lines file
+func (s *JSONLStorage) Open() error {
+	out, err := createOutput(s.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create JSON Lines file: %w", err)
+	}
+	s.out = out
+	return nil
+}
+
+// Write appends a result as a single line
+func (s *JSONLStorage) Write(result crawler.Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to encode JSON: %w", err)
+	}
+	data = append(data, '\n')
+	if _, err := s.out.Write(data); err != nil {
+		return fmt.Errorf("failed to write JSON Lines: %w", err)
+	}
+	return nil
+}
+
+// Close flushes the lines, closes the file and writes the metadata file
+func (s *JSONLStorage) Close() error {
+	if err := s.out.Close(); err != nil {
+		return err
+	}
+	return s.writeSidecar(s.filePath)
+}
+
+// Save writes the crawl results to a JSON Lines file
+func (s *JSONLStorage) Save(results interface{}) error {
+	return saveAll(s, results)
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+// Referrer is a page that links to a broken URL
+type Referrer struct {
+	Page       string `json:"page"`
+	AnchorText string `json:"anchor_text"`
+}
+
+// BrokenLink is a URL that could not be fetched, with the pages that link to it
+type BrokenLink struct {
+	URL           string     `json:"url"`
+	StatusCode    int        `json:"status_code,omitempty"`
+	Error         string     `json:"error"`
+	ErrorCategory string     `json:"error_category"`
+	Referrers     []Referrer `json:"referrers"`
+}
+
+// LinkReport implements Sink by collecting the broken links of a crawl and the pages referring to them.
+// Links are broken when their fetch failed for any reason but a skipped content type or a parse error.
+// Referrers of links that turned out to work are dropped as soon as that is known.
+type LinkReport struct {
+	filePath  string
+	working   map[string]bool       // URLs fetched successfully
+	broken    map[string]BrokenLink // Broken URLs, without referrers
+	referrers map[string][]Referrer // Referrers of URLs not known to work
+}
+
+// NewLinkReport creates a link report, written as JSON to filePath on Close unless filePath is empty
+func NewLinkReport(filePath string) *LinkReport {
+	return &LinkReport{
+		filePath: filePath,
+	}
+}
+
+// Open starts an empty report
+func (r *LinkReport) Open() error {
+	r.working = make(map[string]bool)
+	r.broken = make(map[string]BrokenLink)
+	r.referrers = make(map[string][]Referrer)
+	return nil
+}
+
+// Write records whether a URL works and which URLs its page links to
+func (r *LinkReport) Write(result crawler.Result) error {
+	if isBroken(result) {
+		r.broken[result.URL] = BrokenLink{
+			URL:           result.URL,
+			StatusCode:    result.StatusCode,
+			Error:         result.Error,
+			ErrorCategory: result.ErrorCategory,
+		}
+	} else {
+		r.working[result.URL] = true
+		delete(r.referrers, result.URL)
+	}
+
+	// Remember each page linking to a target once per anchor text
+	for _, link := range outlinks(result) {
+		if r.working[link.URL] {
+			continue
+		}
+		referrer := Referrer{Page: result.URL, AnchorText: link.Text}
+		known := false
+		for _, existing := range r.referrers[link.URL] {
+			if existing == referrer {
+				known = true
+				break
+			}
+		}
+		if !known {
+			r.referrers[link.URL] = append(r.referrers[link.URL], referrer)
+		}
+	}
+	return nil
+}
+
+// isBroken reports whether a result is a link that does not work
+func isBroken(result crawler.Result) bool {
+	switch result.ErrorCategory {
+	case "", crawler.ErrorContentType, crawler.ErrorParse:
+		return false
+	}
+	return true
+}
+
+// Close writes the report file, if one was requested
+func (r *LinkReport) Close() error {
+	if r.filePath == "" {
+		return nil
+	}
+
+	broken := r.Broken()
+	return writeJSONReport(r.filePath, map[string]interface{}{
+		"broken_count": len(broken),
+		"broken":       broken,
+	})
+}
+
+// Broken returns the broken links sorted by URL, each with its referrers sorted by page
+func (r *LinkReport) Broken() []BrokenLink {
+	broken := make([]BrokenLink, 0, len(r.broken))
+	for url, link := range r.broken {
+		link.Referrers = append([]Referrer{}, r.referrers[url]...)
+		sort.Slice(link.Referrers, func(i, j int) bool {
+			if link.Referrers[i].Page != link.Referrers[j].Page {
+				return link.Referrers[i].Page < link.Referrers[j].Page
+			}
+			return link.Referrers[i].AnchorText < link.Referrers[j].AnchorText
+		})
+		broken = append(broken, link)
+	}
+	sort.Slice(broken, func(i, j int) bool { return broken[i].URL < broken[j].URL })
+	return broken
+}
+
+// WriteText writes the broken links as a plain text report grouped by target
+func (r *LinkReport) WriteText(w io.Writer) {
+	for _, link := range r.Broken() {
+		status := link.ErrorCategory
+		if link.StatusCode != 0 {
+			status = fmt.Sprintf("%d %s", link.StatusCode, status)
+		}
+		fmt.Fprintf(w, "%s (%s): %s\n", link.URL, status, link.Error)
+		if len(link.Referrers) == 0 {
+			fmt.Fprintln(w, "  not linked from any crawled page (seed or sitemap URL)")
+		}
+		for _, referrer := range link.Referrers {
+			fmt.Fprintf(w, "  linked from %s as %q\n", referrer.Page, referrer.AnchorText)
+		}
+	}
+}
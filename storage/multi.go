@@ -0,0 +1,68 @@
+package storage
+
+import (
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+// MultiSink streams every result to several sinks, such as the results file and a link graph
+type MultiSink struct {
+	sinks []Sink
+}
+
+// NewMultiSink creates a sink that writes to all of the given sinks in order
+func NewMultiSink(sinks ...Sink) *MultiSink {
+	return &MultiSink{
+		sinks: sinks,
+	}
+}
+
+// SetMetadata passes a value on to the sinks that can record metadata
+func (m *MultiSink) SetMetadata(key string, value interface{}) {
+	for _, sink := range m.sinks {
+		if s, ok := sink.(interface {
+			SetMetadata(key string, value interface{})
+		}); ok {
+			s.SetMetadata(key, value)
+		}
+	}
+}
+
+// Open opens every sink, closing the ones already opened if one fails
+func (m *MultiSink) Open() error {
+	for i, sink := range m.sinks {
+		if err := sink.Open(); err != nil {
+			for _, opened := range m.sinks[:i] {
+				opened.Close()
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Write writes the result to every sink and returns the first error
+func (m *MultiSink) Write(result crawler.Result) error {
+	var first error
+	for _, sink := range m.sinks {
+		if err := sink.Write(result); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Close closes every sink and returns the first error
+func (m *MultiSink) Close() error {
+	var first error
+	for _, sink := range m.sinks {
+		if err := sink.Close(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Save writes the crawl results to every sink
+func (m *MultiSink) Save(results interface{}) error {
+	return saveAll(m, results)
+}
@@ -0,0 +1,72 @@
+package storage
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// output is a buffered output file, gzip-compressed when its name ends in .gz
+type output struct {
+	*bufio.Writer
+	file *os.File
+	gz   *gzip.Writer
+}
+
+// createOutput creates an output file, replacing any existing one
+func createOutput(filePath string) (*output, error) {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	o := &output{file: file}
+	if IsGzipFile(filePath) {
+		o.gz = gzip.NewWriter(file)
+		o.Writer = bufio.NewWriter(o.gz)
+	} else {
+		o.Writer = bufio.NewWriter(file)
+	}
+	return o, nil
+}
+
+// Close flushes all buffered data and closes the file
+func (o *output) Close() error {
+	defer o.file.Close()
+
+	if err := o.Flush(); err != nil {
+		return fmt.Errorf("failed to flush output: %w", err)
+	}
+	if o.gz != nil {
+		if err := o.gz.Close(); err != nil {
+			return fmt.Errorf("failed to finish gzip stream: %w", err)
+		}
+	}
+	return o.file.Close()
+}
+
+// writeJSONReport writes v to filePath as indented JSON, compressed when the name ends in .gz
+func writeJSONReport(filePath string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", filePath, err)
+	}
+
+	out, err := createOutput(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filePath, err)
+	}
+	if _, err := out.Write(append(data, '\n')); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to write %s: %w", filePath, err)
+	}
+	return out.Close()
+}
+
+// IsGzipFile checks if a file path has a .gz extension
+func IsGzipFile(path string) bool {
+	return strings.HasSuffix(strings.ToLower(path), ".gz")
+}
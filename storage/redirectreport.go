@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+// Redirect problems flagged by RedirectReport
+const (
+	RedirectLoop      = "loop"       // The chain leads back to one of its own URLs
+	RedirectTooMany   = "too_many"   // The chain was cut off at the redirect limit
+	RedirectLongChain = "long_chain" // The chain has more hops than the report allows
+)
+
+// FlaggedRedirect is a redirect chain with a problem
+type FlaggedRedirect struct {
+	URL      string             `json:"url"`
+	FinalURL string             `json:"final_url,omitempty"`
+	Problem  string             `json:"problem"`
+	Hops     int                `json:"hops"`
+	Chain    []crawler.Redirect `json:"chain"`
+}
+
+// RedirectReport implements Sink by collecting the redirect loops of a crawl, the chains cut off
+// at the redirect limit and the chains with more than a given number of hops
+type RedirectReport struct {
+	filePath string
+	maxHops  int
+	flagged  []FlaggedRedirect
+}
+
+// NewRedirectReport creates a redirect report that flags chains longer than maxHops,
+// written as JSON to filePath on Close unless filePath is empty
+func NewRedirectReport(filePath string, maxHops int) *RedirectReport {
+	return &RedirectReport{
+		filePath: filePath,
+		maxHops:  maxHops,
+	}
+}
+
+// Open starts an empty report
+func (r *RedirectReport) Open() error {
+	r.flagged = nil
+	return nil
+}
+
+// Write flags the result's redirect chain if it has a problem
+func (r *RedirectReport) Write(result crawler.Result) error {
+	var problem string
+	switch {
+	case result.ErrorCategory == crawler.ErrorRedirectLoop:
+		problem = RedirectLoop
+	case result.ErrorCategory == crawler.ErrorTooManyRedirects:
+		problem = RedirectTooMany
+	case r.maxHops > 0 && len(result.Redirects) > r.maxHops:
+		problem = RedirectLongChain
+	default:
+		return nil
+	}
+
+	r.flagged = append(r.flagged, FlaggedRedirect{
+		URL:      result.URL,
+		FinalURL: result.FinalURL,
+		Problem:  problem,
+		Hops:     len(result.Redirects),
+		Chain:    result.Redirects,
+	})
+	return nil
+}
+
+// Close writes the report file, if one was requested
+func (r *RedirectReport) Close() error {
+	if r.filePath == "" {
+		return nil
+	}
+
+	return writeJSONReport(r.filePath, map[string]interface{}{
+		"max_hops": r.maxHops,
+		"count":    len(r.flagged),
+		"flagged":  r.Flagged(),
+	})
+}
+
+// Flagged returns the flagged redirect chains sorted by URL
+func (r *RedirectReport) Flagged() []FlaggedRedirect {
+	flagged := append([]FlaggedRedirect{}, r.flagged...)
+	sort.Slice(flagged, func(i, j int) bool { return flagged[i].URL < flagged[j].URL })
+	return flagged
+}
+
+// WriteText writes the flagged redirect chains as a plain text report, one hop per line
+func (r *RedirectReport) WriteText(w io.Writer) {
+	for _, flagged := range r.Flagged() {
+		fmt.Fprintf(w, "%s (%s, %d hops)\n", flagged.URL, flagged.Problem, flagged.Hops)
+		for _, hop := range flagged.Chain {
+			fmt.Fprintf(w, "  %d %s -> %s\n", hop.StatusCode, hop.URL, hop.Location)
+		}
+	}
+}
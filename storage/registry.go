@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory creates a storage writing to a file
+type Factory func(filePath string) Storage
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]Factory{
+		"json":    func(filePath string) Storage { return NewJSONStorage(filePath) },
+		"jsonl":   func(filePath string) Storage { return NewJSONLStorage(filePath) },
+		"ndjson":  func(filePath string) Storage { return NewJSONLStorage(filePath) },
+		"csv":     func(filePath string) Storage { return NewCSVStorage(filePath) },
+		"sqlite":  func(filePath string) Storage { return NewSQLiteStorage(filePath) },
+		"sqlite3": func(filePath string) Storage { return NewSQLiteStorage(filePath) },
+		"db":      func(filePath string) Storage { return NewSQLiteStorage(filePath) },
+		"graphml": func(filePath string) Storage { return NewGraphMLStorage(filePath) },
+		"dot":     func(filePath string) Storage { return NewDOTStorage(filePath) },
+		"gv":      func(filePath string) Storage { return NewDOTStorage(filePath) },
+		"edges":   func(filePath string) Storage { return NewEdgeListStorage(filePath) },
+	}
+)
+
+// Register adds or replaces the storage for a format name, which is also matched against file extensions
+func Register(format string, factory Factory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[strings.ToLower(format)] = factory
+}
+
+// Formats returns the registered format names
+func Formats() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	formats := make([]string, 0, len(registry))
+	for format := range registry {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+	return formats
+}
+
+// FormatOf returns the format implied by a file's extension, ignoring a trailing .gz
+func FormatOf(filePath string) string {
+	if IsGzipFile(filePath) {
+		filePath = filePath[:len(filePath)-len(".gz")]
+	}
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(filePath)), ".")
+}
+
+// New creates the storage for a format, or for the file's extension when format is empty
+func New(format, filePath string) (Storage, error) {
+	if format == "" {
+		format = FormatOf(filePath)
+	}
+
+	registryMu.RLock()
+	factory, ok := registry[strings.ToLower(format)]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown output format %q (available: %s)", format, strings.Join(Formats(), ", "))
+	}
+	return factory(filePath), nil
+}
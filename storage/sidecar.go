@@ -0,0 +1,32 @@
+package storage
+
+// sidecar collects crawl metadata, such as the stop reason and summary, for formats that have
+// no place for it, and writes it to a JSON file next to the output (see MetadataPath)
+type sidecar struct {
+	metadata map[string]interface{}
+}
+
+// SetMetadata records a value for the metadata file
+func (s *sidecar) SetMetadata(key string, value interface{}) {
+	if s.metadata == nil {
+		s.metadata = make(map[string]interface{})
+	}
+	s.metadata[key] = value
+}
+
+// writeSidecar writes the metadata file for the output at filePath, if any metadata was set
+func (s *sidecar) writeSidecar(filePath string) error {
+	if len(s.metadata) == 0 {
+		return nil
+	}
+	return writeJSONReport(MetadataPath(filePath), s.metadata)
+}
+
+// MetadataPath returns where the crawl metadata of a JSON Lines, CSV or graph output is written:
+// the output path without any .gz extension, followed by .meta.json
+func MetadataPath(filePath string) string {
+	if IsGzipFile(filePath) {
+		filePath = filePath[:len(filePath)-len(".gz")]
+	}
+	return filePath + ".meta.json"
+}
@@ -0,0 +1,291 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/Taiizor/goCrawler/crawler"
+
+	// Pure-Go SQLite driver, registered as "sqlite"
+	_ "github.com/glebarez/go-sqlite"
+)
+
+// sqliteSchema creates the tables on first use; later runs append to the same database
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id          INTEGER PRIMARY KEY,
+	started_at  TEXT NOT NULL,
+	finished_at TEXT,
+	stop_reason TEXT,
+	page_count  INTEGER NOT NULL DEFAULT 0,
+	metadata    TEXT
+);
+
+CREATE TABLE IF NOT EXISTS pages (
+	id                INTEGER PRIMARY KEY,
+	run_id            INTEGER NOT NULL REFERENCES runs(id),
+	url               TEXT NOT NULL,
+	title             TEXT,
+	status_code       INTEGER,
+	content_length    INTEGER,
+	content_type      TEXT,
+	depth             INTEGER,
+	attempts          INTEGER,
+	asset             INTEGER NOT NULL DEFAULT 0,
+	truncated         INTEGER NOT NULL DEFAULT 0,
+	blocked_by_robots INTEGER NOT NULL DEFAULT 0,
+	sitemap_lastmod   TEXT,
+	sitemap_priority  REAL,
+	crawled_at        TEXT NOT NULL,
+	error             TEXT,
+	error_category    TEXT,
+	check_only        INTEGER NOT NULL DEFAULT 0,
+	final_url         TEXT,
+	redirect_count    INTEGER NOT NULL DEFAULT 0,
+	canonical         TEXT,
+	content_hash      TEXT,
+	simhash           TEXT,
+	duplicate_of      TEXT
+);
+CREATE INDEX IF NOT EXISTS pages_run_url ON pages(run_id, url);
+CREATE INDEX IF NOT EXISTS pages_url ON pages(url);
+CREATE INDEX IF NOT EXISTS pages_status ON pages(run_id, status_code);
+
+CREATE TABLE IF NOT EXISTS links (
+	id          INTEGER PRIMARY KEY,
+	run_id      INTEGER NOT NULL REFERENCES runs(id),
+	source_id   INTEGER NOT NULL REFERENCES pages(id),
+	target_url  TEXT NOT NULL,
+	anchor_text TEXT
+);
+CREATE INDEX IF NOT EXISTS links_source ON links(source_id);
+CREATE INDEX IF NOT EXISTS links_target ON links(run_id, target_url);
+
+CREATE TABLE IF NOT EXISTS redirects (
+	id          INTEGER PRIMARY KEY,
+	run_id      INTEGER NOT NULL REFERENCES runs(id),
+	page_id     INTEGER NOT NULL REFERENCES pages(id),
+	hop         INTEGER NOT NULL,
+	url         TEXT NOT NULL,
+	status_code INTEGER,
+	location    TEXT
+);
+CREATE INDEX IF NOT EXISTS redirects_page ON redirects(page_id);
+`
+
+// sqliteAddedColumns lists columns added after a table was first released; they are added
+// to databases written by older versions when they are opened
+var sqliteAddedColumns = []struct {
+	table, column, definition string
+}{
+	{"pages", "error", "TEXT"},
+	{"pages", "error_category", "TEXT"},
+	{"pages", "check_only", "INTEGER NOT NULL DEFAULT 0"},
+	{"pages", "final_url", "TEXT"},
+	{"pages", "redirect_count", "INTEGER NOT NULL DEFAULT 0"},
+	{"pages", "canonical", "TEXT"},
+	{"pages", "content_hash", "TEXT"},
+	{"pages", "simhash", "TEXT"},
+	{"pages", "duplicate_of", "TEXT"},
+}
+
+// sqliteBatchSize is the number of pages written per transaction
+const sqliteBatchSize = 500
+
+// SQLiteStorage implements Storage interface for SQLite databases.
+// Each crawl is recorded as a row in runs, with its pages, links and redirects in their own tables.
+type SQLiteStorage struct {
+	filePath string
+	metadata map[string]interface{}
+	db       *sql.DB
+	tx       *sql.Tx
+	insPage  *sql.Stmt
+	insLink  *sql.Stmt
+	insHop   *sql.Stmt
+	runID    int64
+	pending  int // Pages in the open transaction
+	count    int
+}
+
+// NewSQLiteStorage creates a new SQLiteStorage instance
+func NewSQLiteStorage(filePath string) *SQLiteStorage {
+	return &SQLiteStorage{
+		filePath: filePath,
+		metadata: make(map[string]interface{}),
+	}
+}
+
+// SetMetadata records a value, such as the crawl's stop reason, with the run
+func (s *SQLiteStorage) SetMetadata(key string, value interface{}) {
+	s.metadata[key] = value
+}
+
+// Open creates the schema if needed and starts a new run
+func (s *SQLiteStorage) Open() error {
+	db, err := sql.Open("sqlite", s.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open SQLite database: %w", err)
+	}
+	// A single connection keeps the pragmas and transactions on one handle
+	db.SetMaxOpenConns(1)
+	s.db = db
+
+	for _, pragma := range []string{"PRAGMA journal_mode=WAL", "PRAGMA foreign_keys=ON"} {
+		if _, err := db.Exec(pragma); err != nil {
+			db.Close()
+			return fmt.Errorf("failed to configure SQLite database: %w", err)
+		}
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return fmt.Errorf("failed to create SQLite schema: %w", err)
+	}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return err
+	}
+
+	res, err := db.Exec("INSERT INTO runs (started_at) VALUES (?)", time.Now().Format(time.RFC3339))
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to record crawl run: %w", err)
+	}
+	s.runID, err = res.LastInsertId()
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to record crawl run: %w", err)
+	}
+	s.count = 0
+
+	return s.begin()
+}
+
+// migrate adds the columns that a database from an older version is missing
+func (s *SQLiteStorage) migrate() error {
+	for _, added := range sqliteAddedColumns {
+		var count int
+		err := s.db.QueryRow("SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?", added.table, added.column).Scan(&count)
+		if err != nil {
+			return fmt.Errorf("failed to inspect SQLite schema: %w", err)
+		}
+		if count > 0 {
+			continue
+		}
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", added.table, added.column, added.definition)); err != nil {
+			return fmt.Errorf("failed to upgrade SQLite schema: %w", err)
+		}
+	}
+	return nil
+}
+
+// begin starts a transaction and prepares the insert statements in it
+func (s *SQLiteStorage) begin() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	s.tx = tx
+	s.pending = 0
+
+	s.insPage, err = tx.Prepare(`INSERT INTO pages (run_id, url, title, status_code, content_length,
+		content_type, depth, attempts, asset, truncated, blocked_by_robots, sitemap_lastmod,
+		sitemap_priority, crawled_at, error, error_category, check_only, final_url, redirect_count,
+		canonical, content_hash, simhash, duplicate_of)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	s.insLink, err = tx.Prepare("INSERT INTO links (run_id, source_id, target_url, anchor_text) VALUES (?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	s.insHop, err = tx.Prepare("INSERT INTO redirects (run_id, page_id, hop, url, status_code, location) VALUES (?, ?, ?, ?, ?, ?)")
+	if err != nil {
+		return fmt.Errorf("failed to prepare statement: %w", err)
+	}
+	return nil
+}
+
+// commit ends the open transaction
+func (s *SQLiteStorage) commit() error {
+	if s.tx == nil {
+		return nil
+	}
+	err := s.tx.Commit()
+	s.tx = nil
+	if err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// Write inserts a page and its links
+func (s *SQLiteStorage) Write(result crawler.Result) error {
+	res, err := s.insPage.Exec(s.runID, result.URL, result.Title, result.StatusCode, result.ContentLength,
+		result.ContentType, result.Depth, result.Attempts, result.Asset, result.Truncated, result.BlockedByRobots,
+		result.SitemapLastMod, result.SitemapPriority, result.Timestamp.Format(time.RFC3339), nullString(result.Error),
+		nullString(result.ErrorCategory), result.CheckOnly, nullString(result.FinalURL), len(result.Redirects),
+		nullString(metadata(result).Canonical), nullString(result.ContentHash), nullString(result.SimHash), nullString(result.DuplicateOf))
+	if err != nil {
+		return fmt.Errorf("failed to insert page: %w", err)
+	}
+	pageID, err := res.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("failed to insert page: %w", err)
+	}
+
+	for _, link := range outlinks(result) {
+		if _, err := s.insLink.Exec(s.runID, pageID, link.URL, link.Text); err != nil {
+			return fmt.Errorf("failed to insert link: %w", err)
+		}
+	}
+	for i, hop := range result.Redirects {
+		if _, err := s.insHop.Exec(s.runID, pageID, i+1, hop.URL, hop.StatusCode, hop.Location); err != nil {
+			return fmt.Errorf("failed to insert redirect: %w", err)
+		}
+	}
+	s.count++
+
+	// Commit in batches to keep transactions small
+	s.pending++
+	if s.pending >= sqliteBatchSize {
+		if err := s.commit(); err != nil {
+			return err
+		}
+		return s.begin()
+	}
+	return nil
+}
+
+// Close commits the remaining pages, completes the run and closes the database
+func (s *SQLiteStorage) Close() error {
+	defer s.db.Close()
+
+	if err := s.commit(); err != nil {
+		return err
+	}
+
+	metadata, err := json.Marshal(s.metadata)
+	if err != nil {
+		return fmt.Errorf("failed to encode run metadata: %w", err)
+	}
+	stopReason, _ := s.metadata["stop_reason"].(string)
+	_, err = s.db.Exec("UPDATE runs SET finished_at = ?, stop_reason = ?, page_count = ?, metadata = ? WHERE id = ?",
+		time.Now().Format(time.RFC3339), stopReason, s.count, string(metadata), s.runID)
+	if err != nil {
+		return fmt.Errorf("failed to complete crawl run: %w", err)
+	}
+	return s.db.Close()
+}
+
+// nullString stores an empty string as NULL
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// Save writes the crawl results to a SQLite database as a new run
+func (s *SQLiteStorage) Save(results interface{}) error {
+	return saveAll(s, results)
+}
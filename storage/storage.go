@@ -1,15 +1,67 @@
 package storage
 
 import (
+	"fmt"
 	"path/filepath"
 	"strings"
+
+	"github.com/Taiizor/goCrawler/crawler"
 )
 
-// Storage is the interface for saving crawler results
+// Sink is the interface for streaming crawler results to a file as they are crawled
+type Sink interface {
+	Open() error
+	Write(result crawler.Result) error
+	Close() error
+}
+
+// Storage is the interface for saving crawler results, either streamed through the
+// Sink methods or all at once with Save
 type Storage interface {
+	Sink
 	Save(results interface{}) error
 }
 
+// saveAll writes a batch of results through a sink
+func saveAll(s Sink, results interface{}) error {
+	typed, ok := results.([]crawler.Result)
+	if !ok {
+		return fmt.Errorf("expected []crawler.Result but got %T", results)
+	}
+
+	if err := s.Open(); err != nil {
+		return err
+	}
+	for _, result := range typed {
+		if err := s.Write(result); err != nil {
+			s.Close()
+			return err
+		}
+	}
+	return s.Close()
+}
+
+// outlinks returns a result's links with their anchor text, falling back to the plain
+// link list for results without it
+func outlinks(result crawler.Result) []crawler.Link {
+	if result.Outlinks != nil {
+		return result.Outlinks
+	}
+	links := make([]crawler.Link, 0, len(result.Links))
+	for _, link := range result.Links {
+		links = append(links, crawler.Link{URL: link})
+	}
+	return links
+}
+
+// metadata returns a result's page metadata, empty for results that aren't parsed pages
+func metadata(result crawler.Result) crawler.Metadata {
+	if result.Metadata != nil {
+		return *result.Metadata
+	}
+	return crawler.Metadata{}
+}
+
 // IsJSONFile checks if a file path has a .json extension
 func IsJSONFile(path string) bool {
 	return strings.ToLower(filepath.Ext(path)) == ".json"
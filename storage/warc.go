@@ -0,0 +1,246 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+// warcDateFormat is the WARC-Date format, UTC with fractional seconds as allowed by WARC 1.1
+const warcDateFormat = "2006-01-02T15:04:05.000000Z"
+
+// warcDescription tells readers of the warcinfo record how response records are made
+const warcDescription = "Response records are rebuilt from the parsed HTTP response: headers are " +
+	"re-serialized in canonical case and sorted order and chunked bodies are stored decoded, " +
+	"so records are not byte-for-byte copies of the exchange"
+
+// errWARCClosed is returned for exchanges archived after Close
+var errWARCClosed = errors.New("WARC file is closed")
+
+// warcField is a named header of a WARC record
+type warcField struct {
+	name, value string
+}
+
+// WARCWriter implements crawler.Archiver by writing WARC 1.1 files.
+// Every exchange becomes a request, a response and a metadata record. Records are rebuilt from
+// the parsed request and response rather than captured from the connection (see warcDescription).
+// A .gz file name compresses each record as its own gzip member, as WARC readers expect.
+type WARCWriter struct {
+	filePath  string
+	mu        sync.Mutex
+	file      *os.File
+	writer    *bufio.Writer
+	gz        *gzip.Writer
+	warcinfo  string // Record ID of the warcinfo record
+	closed    bool
+	userAgent string
+}
+
+// NewWARCWriter creates a new WARCWriter instance
+func NewWARCWriter(filePath string) *WARCWriter {
+	return &WARCWriter{
+		filePath: filePath,
+	}
+}
+
+// SetUserAgent records the crawler's User-Agent in the warcinfo record
+func (w *WARCWriter) SetUserAgent(userAgent string) {
+	w.userAgent = userAgent
+}
+
+// Open creates the WARC file and writes its warcinfo record
+func (w *WARCWriter) Open() error {
+	file, err := os.Create(w.filePath)
+	if err != nil {
+		return fmt.Errorf("failed to create WARC file: %w", err)
+	}
+	w.file = file
+	w.writer = bufio.NewWriter(file)
+	if IsGzipFile(w.filePath) {
+		w.gz = gzip.NewWriter(w.writer)
+	}
+
+	// Describe the crawl that produced the file
+	var info bytes.Buffer
+	info.WriteString("software: goCrawler\r\n")
+	info.WriteString("format: WARC File Format 1.1\r\n")
+	info.WriteString("conformsTo: https://iipc.github.io/warc-specifications/specifications/warc-format/warc-1.1/\r\n")
+	if w.userAgent != "" {
+		fmt.Fprintf(&info, "http-header-user-agent: %s\r\n", w.userAgent)
+	}
+	info.WriteString("description: " + warcDescription + "\r\n")
+
+	w.warcinfo = newRecordID()
+	return w.writeRecord([]warcField{
+		{"WARC-Type", "warcinfo"},
+		{"WARC-Record-ID", w.warcinfo},
+		{"WARC-Date", time.Now().UTC().Format(warcDateFormat)},
+		{"WARC-Filename", filepath.Base(w.filePath)},
+		{"Content-Type", "application/warc-fields"},
+	}, info.Bytes())
+}
+
+// Archive writes the request, response and metadata records of an exchange
+func (w *WARCWriter) Archive(ex *crawler.Exchange) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errWARCClosed
+	}
+
+	date := ex.Date.UTC().Format(warcDateFormat)
+	targetURI := ex.Request.URL.String()
+
+	// The response comes first, so the other records can refer to it
+	var response bytes.Buffer
+	fmt.Fprintf(&response, "HTTP/%d.%d %s\r\n", ex.Response.ProtoMajor, ex.Response.ProtoMinor, ex.Response.Status)
+	ex.Response.Header.Write(&response)
+	response.WriteString("\r\n")
+	response.Write(ex.Body)
+
+	responseID := newRecordID()
+	fields := []warcField{
+		{"WARC-Type", "response"},
+		{"WARC-Record-ID", responseID},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", targetURI},
+		{"WARC-Warcinfo-ID", w.warcinfo},
+	}
+	if ex.IP != "" {
+		fields = append(fields, warcField{"WARC-IP-Address", ex.IP})
+	}
+	fields = append(fields,
+		warcField{"WARC-Block-Digest", digest(response.Bytes())},
+		warcField{"WARC-Payload-Digest", digest(ex.Body)},
+		warcField{"Content-Type", "application/http;msgtype=response"},
+	)
+	if ex.Truncated {
+		fields = append(fields, warcField{"WARC-Truncated", "length"})
+	}
+	if err := w.writeRecord(fields, response.Bytes()); err != nil {
+		return err
+	}
+
+	// The request as it was sent
+	// The client leaves Host empty on redirected requests, which are sent to the URL's host
+	host := ex.Request.Host
+	if host == "" {
+		host = ex.Request.URL.Host
+	}
+	var request bytes.Buffer
+	fmt.Fprintf(&request, "%s %s HTTP/1.1\r\n", ex.Request.Method, ex.Request.URL.RequestURI())
+	fmt.Fprintf(&request, "Host: %s\r\n", host)
+	ex.Request.Header.Write(&request)
+	request.WriteString("\r\n")
+
+	fields = []warcField{
+		{"WARC-Type", "request"},
+		{"WARC-Record-ID", newRecordID()},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", targetURI},
+		{"WARC-Concurrent-To", responseID},
+		{"WARC-Warcinfo-ID", w.warcinfo},
+	}
+	if ex.IP != "" {
+		fields = append(fields, warcField{"WARC-IP-Address", ex.IP})
+	}
+	fields = append(fields,
+		warcField{"WARC-Block-Digest", digest(request.Bytes())},
+		warcField{"Content-Type", "application/http;msgtype=request"},
+	)
+	if err := w.writeRecord(fields, request.Bytes()); err != nil {
+		return err
+	}
+
+	// Crawl details that are not part of the HTTP exchange
+	var metadata bytes.Buffer
+	fmt.Fprintf(&metadata, "fetchTimeMs: %d\r\n", ex.Duration.Milliseconds())
+	for _, link := range ex.Outlinks {
+		fmt.Fprintf(&metadata, "outlink: %s\r\n", link.URL)
+	}
+
+	return w.writeRecord([]warcField{
+		{"WARC-Type", "metadata"},
+		{"WARC-Record-ID", newRecordID()},
+		{"WARC-Date", date},
+		{"WARC-Target-URI", targetURI},
+		{"WARC-Concurrent-To", responseID},
+		{"WARC-Warcinfo-ID", w.warcinfo},
+		{"WARC-Block-Digest", digest(metadata.Bytes())},
+		{"Content-Type", "application/warc-fields"},
+	}, metadata.Bytes())
+}
+
+// writeRecord writes a single record, as its own gzip member when compressing.
+// Callers must hold mu, except while opening the file.
+func (w *WARCWriter) writeRecord(fields []warcField, block []byte) error {
+	var out io.Writer = w.writer
+	if w.gz != nil {
+		w.gz.Reset(w.writer)
+		out = w.gz
+	}
+
+	var header bytes.Buffer
+	header.WriteString("WARC/1.1\r\n")
+	for _, field := range fields {
+		fmt.Fprintf(&header, "%s: %s\r\n", field.name, field.value)
+	}
+	fmt.Fprintf(&header, "Content-Length: %s\r\n\r\n", strconv.Itoa(len(block)))
+
+	for _, part := range [][]byte{header.Bytes(), block, []byte("\r\n\r\n")} {
+		if _, err := out.Write(part); err != nil {
+			return fmt.Errorf("failed to write WARC record: %w", err)
+		}
+	}
+	if w.gz != nil {
+		if err := w.gz.Close(); err != nil {
+			return fmt.Errorf("failed to compress WARC record: %w", err)
+		}
+	}
+	return nil
+}
+
+// Close flushes the records and closes the file
+func (w *WARCWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer w.file.Close()
+
+	if err := w.writer.Flush(); err != nil {
+		return fmt.Errorf("failed to write WARC file: %w", err)
+	}
+	return w.file.Close()
+}
+
+// digest returns the SHA-1 digest of data in the "sha1:<base32>" form used by WARC
+func digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// newRecordID returns a random UUID URN for WARC-Record-ID
+func newRecordID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
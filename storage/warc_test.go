@@ -0,0 +1,252 @@
+package storage
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/base32"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Taiizor/goCrawler/crawler"
+)
+
+// warcRecord is a record read back from a WARC file
+type warcRecord struct {
+	headers map[string]string
+	block   []byte
+}
+
+// readWARC splits a WARC file into records, checking the framing of each one
+func readWARC(t *testing.T, filePath string) []warcRecord {
+	t.Helper()
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if IsGzipFile(filePath) {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = gz
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []warcRecord
+	for len(data) > 0 {
+		end := bytes.Index(data, []byte("\r\n\r\n"))
+		if end < 0 {
+			t.Fatalf("record %d has no end of headers", len(records))
+		}
+		lines := strings.Split(string(data[:end]), "\r\n")
+		if lines[0] != "WARC/1.1" {
+			t.Fatalf("record %d starts with %q, want WARC/1.1", len(records), lines[0])
+		}
+		headers := make(map[string]string)
+		for _, line := range lines[1:] {
+			name, value, ok := strings.Cut(line, ": ")
+			if !ok {
+				t.Fatalf("record %d has malformed header %q", len(records), line)
+			}
+			headers[name] = value
+		}
+		length, err := strconv.Atoi(headers["Content-Length"])
+		if err != nil {
+			t.Fatalf("record %d has Content-Length %q", len(records), headers["Content-Length"])
+		}
+
+		data = data[end+4:]
+		if len(data) < length+4 {
+			t.Fatalf("record %d is shorter than its Content-Length %d", len(records), length)
+		}
+		block := data[:length]
+		if !bytes.Equal(data[length:length+4], []byte("\r\n\r\n")) {
+			t.Fatalf("record %d block is not followed by CRLF CRLF", len(records))
+		}
+		data = data[length+4:]
+		records = append(records, warcRecord{headers: headers, block: block})
+	}
+	return records
+}
+
+// sha1Digest returns the expected WARC digest of data
+func sha1Digest(data []byte) string {
+	sum := sha1.Sum(data)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestWARCWriterRecords(t *testing.T) {
+	tests := []struct {
+		name      string
+		fileName  string
+		host      string
+		truncated bool
+		wantHost  string
+	}{
+		{"plain", "crawl.warc", "example.com", false, "example.com"},
+		{"gzip members", "crawl.warc.gz", "example.com", false, "example.com"},
+		{"truncated body", "crawl.warc", "example.com", true, "example.com"},
+		{"redirected request without Host", "crawl.warc", "", false, "example.com:8080"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target, _ := url.Parse("http://example.com:8080/page?q=1")
+			request := &http.Request{
+				Method: http.MethodGet,
+				URL:    target,
+				Host:   tt.host,
+				Header: http.Header{"User-Agent": {"TestBot"}},
+			}
+			body := []byte("<html><a href=\"/next\">next</a></html>")
+			response := &http.Response{
+				Status:     "200 OK",
+				StatusCode: http.StatusOK,
+				ProtoMajor: 1,
+				ProtoMinor: 1,
+				Header:     http.Header{"Content-Type": {"text/html"}},
+				Request:    request,
+			}
+
+			filePath := filepath.Join(t.TempDir(), tt.fileName)
+			w := NewWARCWriter(filePath)
+			w.SetUserAgent("TestBot")
+			if err := w.Open(); err != nil {
+				t.Fatal(err)
+			}
+			err := w.Archive(&crawler.Exchange{
+				Request:   request,
+				Response:  response,
+				Body:      body,
+				Truncated: tt.truncated,
+				IP:        "192.0.2.1",
+				Date:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				Duration:  42 * time.Millisecond,
+				Outlinks:  []crawler.Link{{URL: "http://example.com:8080/next"}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Archive(&crawler.Exchange{}); err != errWARCClosed {
+				t.Errorf("Archive after Close = %v, want %v", err, errWARCClosed)
+			}
+
+			records := readWARC(t, filePath)
+			types := make([]string, len(records))
+			for i, record := range records {
+				types[i] = record.headers["WARC-Type"]
+			}
+			if got := strings.Join(types, ","); got != "warcinfo,response,request,metadata" {
+				t.Fatalf("record types = %s", got)
+			}
+			info, resp, req, meta := records[0], records[1], records[2], records[3]
+
+			// Every record but warcinfo digests its block and points back at the warcinfo record
+			for _, record := range records[1:] {
+				if got, want := record.headers["WARC-Block-Digest"], sha1Digest(record.block); got != want {
+					t.Errorf("%s block digest = %s, want %s", record.headers["WARC-Type"], got, want)
+				}
+				if got := record.headers["WARC-Warcinfo-ID"]; got != info.headers["WARC-Record-ID"] {
+					t.Errorf("%s warcinfo ID = %s, want %s", record.headers["WARC-Type"], got, info.headers["WARC-Record-ID"])
+				}
+				if got := record.headers["WARC-Target-URI"]; got != target.String() {
+					t.Errorf("%s target URI = %s, want %s", record.headers["WARC-Type"], got, target)
+				}
+				if got := record.headers["WARC-Date"]; got != "2024-01-02T03:04:05.000000Z" {
+					t.Errorf("%s date = %s", record.headers["WARC-Type"], got)
+				}
+			}
+			if got := info.headers["WARC-Filename"]; got != tt.fileName {
+				t.Errorf("warcinfo file name = %s, want %s", got, tt.fileName)
+			}
+			if !bytes.Contains(info.block, []byte("description: "+warcDescription+"\r\n")) {
+				t.Errorf("warcinfo block lacks the description:\n%s", info.block)
+			}
+			if !bytes.Contains(info.block, []byte("http-header-user-agent: TestBot\r\n")) {
+				t.Errorf("warcinfo block lacks the user agent:\n%s", info.block)
+			}
+
+			// The response block is the status line, headers and body; the payload digest covers the body only
+			if !bytes.HasPrefix(resp.block, []byte("HTTP/1.1 200 OK\r\nContent-Type: text/html\r\n\r\n")) {
+				t.Errorf("response block = %q", resp.block)
+			}
+			if !bytes.HasSuffix(resp.block, body) {
+				t.Errorf("response block does not end with the body")
+			}
+			if got, want := resp.headers["WARC-Payload-Digest"], sha1Digest(body); got != want {
+				t.Errorf("payload digest = %s, want %s", got, want)
+			}
+			if got := resp.headers["WARC-IP-Address"]; got != "192.0.2.1" {
+				t.Errorf("IP address = %s", got)
+			}
+			wantTruncated := ""
+			if tt.truncated {
+				wantTruncated = "length"
+			}
+			if got := resp.headers["WARC-Truncated"]; got != wantTruncated {
+				t.Errorf("WARC-Truncated = %q, want %q", got, wantTruncated)
+			}
+
+			// The request and metadata records are concurrent to the response
+			wantRequest := "GET /page?q=1 HTTP/1.1\r\nHost: " + tt.wantHost + "\r\nUser-Agent: TestBot\r\n\r\n"
+			if string(req.block) != wantRequest {
+				t.Errorf("request block = %q, want %q", req.block, wantRequest)
+			}
+			for _, record := range []warcRecord{req, meta} {
+				if got := record.headers["WARC-Concurrent-To"]; got != resp.headers["WARC-Record-ID"] {
+					t.Errorf("%s concurrent to %s, want %s", record.headers["WARC-Type"], got, resp.headers["WARC-Record-ID"])
+				}
+			}
+			wantMetadata := "fetchTimeMs: 42\r\noutlink: http://example.com:8080/next\r\n"
+			if string(meta.block) != wantMetadata {
+				t.Errorf("metadata block = %q, want %q", meta.block, wantMetadata)
+			}
+		})
+	}
+}
+
+func TestWARCWriterGzipMembers(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "crawl.warc.gz")
+	w := NewWARCWriter(filePath)
+	if err := w.Open(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The warcinfo record alone must be a complete gzip member
+	file, err := os.Open(filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz.Multistream(false)
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, []byte("WARC/1.1\r\nWARC-Type: warcinfo\r\n")) || !bytes.HasSuffix(data, []byte("\r\n\r\n")) {
+		t.Errorf("first gzip member = %q, want one warcinfo record", data)
+	}
+}